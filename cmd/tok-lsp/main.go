@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+	"github.com/aiq/tok/lsp"
+)
+
+func main() {
+	srv := lsp.NewServer()
+	srv.Register("json", []string{".json"}, func() tok.Grammar { return grammar.JSON() })
+	srv.Register("jsonc", []string{".jsonc"}, func() tok.Grammar { return grammar.JSONC() })
+	srv.Register("json5", []string{".json5"}, func() tok.Grammar { return grammar.JSON5() })
+	srv.Register("lua", []string{".lua"}, func() tok.Grammar { return grammar.Lua() })
+	srv.Register("mxt", []string{".mxt"}, func() tok.Grammar { return grammar.MXT() })
+	if err := srv.Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
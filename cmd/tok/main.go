@@ -0,0 +1,527 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/debug"
+	"github.com/aiq/tok/grammar"
+)
+
+const usage = `usage: tok <command> [flags] [file ...]
+
+commands:
+  parse    checks if the files can be read with the grammar
+  tree     prints the Graph of a file as indented text or JSON
+  flame    prints the FlameStack of a file, or the Graph as Chrome trace or speedscope file
+  trace    prints the Log of the rules with a preview of the text, or as Chrome trace or speedscope file
+  grammar  prints the GrammarLines of the grammar
+  profile  prints the statistics of the rules, optional as pprof profile
+  cover    prints which rules and alternatives the files use per grammar, optional as HTML report
+  gen      prints random texts of a grammar, one per line
+  debug    steps interactively through the rules, the commands are read from stdin
+
+The grammar is json, jsonc, json5, lua, mxt or the path of a grammar file.
+Without -g is the grammar selected via the file extension.
+
+exit codes: 0 success, 1 invalid input, 2 usage or I/O error
+`
+
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+type command func(env *env, args []string) int
+
+var commands = map[string]command{
+	"parse":   parseCmd,
+	"tree":    treeCmd,
+	"flame":   flameCmd,
+	"trace":   traceCmd,
+	"grammar": grammarCmd,
+	"profile": profileCmd,
+	"cover":   coverCmd,
+	"gen":     genCmd,
+	"debug":   debugCmd,
+}
+
+// env contains the streams of the commands.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (e *env) fail(code int, format string, a ...interface{}) int {
+	fmt.Fprintf(e.stderr, "tok: "+format+"\n", a...)
+	return code
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin, stdout, stderr}
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(stderr, usage)
+		return e.fail(exitUsage, "unknown command %q", args[0])
+	}
+	return cmd(e, args[1:])
+}
+
+func (e *env) flags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	g := fs.String("g", "", "json, jsonc, json5, lua, mxt or the path of a grammar file")
+	return fs, g
+}
+
+// ------------------------------------------------------------------------------
+// input is a file that will be read with a Grammar.
+type input struct {
+	name string
+	src  *tok.Source
+	sca  *tok.Scanner
+	g    tok.Grammar
+}
+
+// grammarName returns gname or the name of the Grammar for the extension of filename if gname is empty.
+func grammarName(gname string, filename string) (string, error) {
+	if gname != "" {
+		return gname, nil
+	}
+	gname = strings.TrimPrefix(filepath.Ext(filename), ".")
+	switch gname {
+	case "json", "jsonc", "json5", "lua", "mxt":
+		return gname, nil
+	}
+	return "", fmt.Errorf("no grammar for %q, use -g", filename)
+}
+
+// grammarFor opens the Grammar gname or the Grammar for the extension of filename if gname is empty.
+func grammarFor(gname string, filename string) (tok.Grammar, error) {
+	gname, err := grammarName(gname, filename)
+	if err != nil {
+		return nil, err
+	}
+	return grammar.Open(gname)
+}
+
+func openInput(gname string, filename string) (*input, error) {
+	g, err := grammarFor(gname, filename)
+	if err != nil {
+		return nil, err
+	}
+	return readInput(g, filename)
+}
+
+func readInput(g tok.Grammar, filename string) (*input, error) {
+	text, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	src := tok.NewFileSource(filename, string(text))
+	return &input{
+		name: filename,
+		src:  src,
+		sca:  tok.NewSourceScanner(src),
+		g:    g,
+	}, nil
+}
+
+// parse reads the whole text with the Grammar.
+func (in *input) parse() error {
+	if err := in.sca.Use(in.g); err != nil {
+		return in.src.WrapError(err)
+	}
+	return in.src.WrapError(in.sca.ErrorIfFalse(in.sca.AtEnd(), "end of text"))
+}
+
+// graph parses the text and builds a Graph with all Rules of the Grammar.
+func (in *input) graph() (*tok.Graph, error) {
+	basket := in.sca.NewBasketFor(in.g)
+	if err := in.parse(); err != nil {
+		return nil, err
+	}
+	return tok.BuildGraph(in.name, basket.Picked()), nil
+}
+
+// single parses the flags of a command that expects one file.
+func (e *env) single(fs *flag.FlagSet, g *string, args []string) (*input, int) {
+	if err := fs.Parse(args); err != nil {
+		return nil, exitUsage
+	}
+	if fs.NArg() != 1 {
+		return nil, e.fail(exitUsage, "%s expects one file", fs.Name())
+	}
+	in, err := openInput(*g, fs.Arg(0))
+	if err != nil {
+		return nil, e.fail(exitUsage, "%v", err)
+	}
+	return in, exitOK
+}
+
+// ------------------------------------------------------------------------------
+func parseCmd(e *env, args []string) int {
+	fs, g := e.flags("parse")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		return e.fail(exitUsage, "parse expects at least one file")
+	}
+	code := exitOK
+	for _, filename := range fs.Args() {
+		in, err := openInput(*g, filename)
+		if err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		if err := in.parse(); err != nil {
+			code = e.fail(exitInvalid, "%v", err)
+		}
+	}
+	return code
+}
+
+type jsonNode struct {
+	Info  string      `json:"info"`
+	From  int         `json:"from"`
+	To    int         `json:"to"`
+	Text  string      `json:"text,omitempty"`
+	Nodes []*jsonNode `json:"nodes,omitempty"`
+}
+
+func makeJSONNode(sca *tok.Scanner, n *tok.Node) *jsonNode {
+	res := &jsonNode{Info: n.Info, From: int(n.From()), To: int(n.To())}
+	if len(n.Nodes) == 0 {
+		res.Text = sca.Get(n.Token)
+	}
+	for _, sub := range n.Nodes {
+		res.Nodes = append(res.Nodes, makeJSONNode(sca, sub))
+	}
+	return res
+}
+
+func printNode(w io.Writer, sca *tok.Scanner, n *tok.Node, level int) {
+	fmt.Fprintf(w, "%s%s", strings.Repeat("  ", level), n.String())
+	if len(n.Nodes) == 0 {
+		fmt.Fprintf(w, " %s", strconv.Quote(sca.Get(n.Token)))
+	}
+	fmt.Fprintln(w)
+	for _, sub := range n.Nodes {
+		printNode(w, sca, sub, level+1)
+	}
+}
+
+func treeCmd(e *env, args []string) int {
+	fs, g := e.flags("tree")
+	asJSON := fs.Bool("json", false, "prints the Graph as JSON")
+	in, code := e.single(fs, g, args)
+	if in == nil {
+		return code
+	}
+	graph, err := in.graph()
+	if err != nil {
+		return e.fail(exitInvalid, "%v", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(makeJSONNode(in.sca, graph.Root)); err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		return exitOK
+	}
+	printNode(e.stdout, in.sca, graph.Root, 0)
+	return exitOK
+}
+
+func flameCmd(e *env, args []string) int {
+	fs, g := e.flags("flame")
+	format := fs.String("format", "stack", "stack, chrome or speedscope")
+	in, code := e.single(fs, g, args)
+	if in == nil {
+		return code
+	}
+	graph, err := in.graph()
+	if err != nil {
+		return e.fail(exitInvalid, "%v", err)
+	}
+	switch *format {
+	case "stack":
+		_, err = fmt.Fprint(e.stdout, graph.FlameStack())
+	case "chrome":
+		err = graph.WriteChromeTrace(e.stdout)
+	case "speedscope":
+		err = graph.WriteSpeedscope(e.stdout, in.name)
+	default:
+		return e.fail(exitUsage, "unknown format %q", *format)
+	}
+	if err != nil {
+		return e.fail(exitUsage, "%v", err)
+	}
+	return exitOK
+}
+
+var axes = map[string]tok.TraceAxis{
+	"step":   tok.StepAxis,
+	"offset": tok.OffsetAxis,
+	"time":   tok.TimeAxis,
+}
+
+// preview returns up to n runes of str that start at i.
+func preview(str string, i int, n int) string {
+	for j := range str[i:] {
+		if n == 0 {
+			return str[i : i+j]
+		}
+		n--
+	}
+	return str[i:]
+}
+
+func traceCmd(e *env, args []string) int {
+	fs, g := e.flags("trace")
+	n := fs.Int("n", 16, "number of runes in the preview")
+	format := fs.String("format", "text", "text, chrome or speedscope")
+	axisName := fs.String("axis", "step", "step, offset or time as time axis of chrome or speedscope")
+	in, code := e.single(fs, g, args)
+	if in == nil {
+		return code
+	}
+	axis, ok := axes[*axisName]
+	if !ok {
+		return e.fail(exitUsage, "unknown axis %q", *axisName)
+	}
+	l := tok.MonitorGrammar(in.g)
+	err := in.parse()
+	var werr error
+	switch *format {
+	case "text":
+		text := in.sca.Source().Text()
+		for _, entry := range l.Entries {
+			fmt.Fprintln(e.stdout, entry, ">", strconv.Quote(preview(text, entry.EnterAt, *n)))
+		}
+	case "chrome":
+		werr = l.WriteChromeTrace(e.stdout, axis)
+	case "speedscope":
+		werr = l.WriteSpeedscope(e.stdout, in.name, axis)
+	default:
+		return e.fail(exitUsage, "unknown format %q", *format)
+	}
+	if werr != nil {
+		return e.fail(exitUsage, "%v", werr)
+	}
+	if err != nil {
+		return e.fail(exitInvalid, "%v", err)
+	}
+	return exitOK
+}
+
+func profileCmd(e *env, args []string) int {
+	fs, g := e.flags("profile")
+	out := fs.String("pprof", "", "writes a pprof profile to the file")
+	in, code := e.single(fs, g, args)
+	if in == nil {
+		return code
+	}
+	p := tok.ProfileGrammar(in.g)
+	in.sca.Tracker = p
+	parseErr := in.parse()
+	if err := p.WriteText(e.stdout); err != nil {
+		return e.fail(exitUsage, "%v", err)
+	}
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		err = p.WritePprof(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+	}
+	if parseErr != nil {
+		return e.fail(exitInvalid, "%v", parseErr)
+	}
+	return exitOK
+}
+
+func coverCmd(e *env, args []string) int {
+	fs, g := e.flags("cover")
+	out := fs.String("html", "", "writes a HTML report to the file")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		return e.fail(exitUsage, "cover expects at least one file")
+	}
+	names := []string{}
+	grammars := map[string]tok.Grammar{}
+	covers := map[string]*tok.Coverage{}
+	code := exitOK
+	for _, filename := range fs.Args() {
+		gname, err := grammarName(*g, filename)
+		if err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		gr, ok := grammars[gname]
+		if !ok {
+			if gr, err = grammar.Open(gname); err != nil {
+				return e.fail(exitUsage, "%v", err)
+			}
+			names = append(names, gname)
+			grammars[gname] = gr
+			covers[gname] = tok.CoverGrammar(gr)
+		}
+		in, err := readInput(gr, filename)
+		if err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		if err := in.parse(); err != nil {
+			code = e.fail(exitInvalid, "%v", err)
+		}
+	}
+	for _, gname := range names {
+		if len(names) > 1 {
+			fmt.Fprintf(e.stdout, "== %s\n", gname)
+		}
+		if err := covers[gname].WriteText(e.stdout); err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		if *out == "" {
+			continue
+		}
+		filename := *out
+		if len(names) > 1 {
+			ext := filepath.Ext(filename)
+			filename = strings.TrimSuffix(filename, ext) + "." + filepath.Base(gname) + ext
+		}
+		if err := writeHTML(covers[gname], filename); err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+	}
+	return code
+}
+
+func writeHTML(c *tok.Coverage, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = c.WriteHTML(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func grammarCmd(e *env, args []string) int {
+	fs, g := e.flags("grammar")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	name := *g
+	if fs.NArg() == 1 && name == "" {
+		name = fs.Arg(0)
+	} else if fs.NArg() != 0 || name == "" {
+		return e.fail(exitUsage, "grammar expects one grammar")
+	}
+	gr, err := grammar.Open(name)
+	if err != nil {
+		return e.fail(exitUsage, "%v", err)
+	}
+	for _, line := range tok.GrammarLines(gr.Grammar()) {
+		fmt.Fprintln(e.stdout, line)
+	}
+	return exitOK
+}
+
+func genCmd(e *env, args []string) int {
+	fs, g := e.flags("gen")
+	seed := fs.Int64("seed", 1, "seed of the random decisions")
+	n := fs.Int("n", 1, "number of texts")
+	depth := fs.Int("depth", 16, "maximal nesting of rules")
+	repeat := fs.Int("repeat", 4, "maximal number of optional items in repetitions")
+	rule := fs.String("rule", "", "the rule to start with, the first rule by default")
+	min := fs.Bool("min", false, "shrinks the texts to the shortest text of the grammar")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	name := *g
+	if fs.NArg() == 1 && name == "" {
+		name = fs.Arg(0)
+	} else if fs.NArg() != 0 || name == "" {
+		return e.fail(exitUsage, "gen expects one grammar")
+	}
+	gr, err := grammar.Open(name)
+	if err != nil {
+		return e.fail(exitUsage, "%v", err)
+	}
+	var start tok.Reader = gr
+	if *rule != "" {
+		start = nil
+		for _, r := range gr.Grammar() {
+			if r.Name == *rule {
+				start = r
+			}
+		}
+		if start == nil {
+			return e.fail(exitUsage, "unknown rule %q", *rule)
+		}
+	}
+	gen := tok.NewGenerator(*seed)
+	gen.MaxDepth = *depth
+	gen.MaxRepeat = *repeat
+	for i := 0; i < *n; i++ {
+		text, err := gen.Generate(start)
+		if err == nil && *min {
+			text, err = gen.Shrink(start, func(string) bool { return true })
+		}
+		if err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+		fmt.Fprintln(e.stdout, text)
+	}
+	return exitOK
+}
+
+func debugCmd(e *env, args []string) int {
+	fs, g := e.flags("debug")
+	b := fs.String("b", "", "comma separated list of rules with a breakpoint")
+	in, code := e.single(fs, g, args)
+	if in == nil {
+		return code
+	}
+	d := debug.New(in.g, e.stdin, e.stdout)
+	if *b != "" {
+		if err := d.Break(strings.Split(*b, ",")...); err != nil {
+			return e.fail(exitUsage, "%v", err)
+		}
+	}
+	err := d.Run(in.src)
+	if err == debug.ErrQuit {
+		return exitOK
+	} else if err != nil {
+		return e.fail(exitInvalid, "%v", err)
+	}
+	return exitOK
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeFile(t, dir, "valid.json", `{"a": [1, 2]}`)
+	invalid := writeFile(t, dir, "invalid.json", "{\n\"a\" 1}")
+	list := writeFile(t, dir, "list.tokg", "list = '[' ?(DIGIT *(',' DIGIT)) ']'\n")
+	data := writeFile(t, dir, "data.txt", "[1,2]")
+	script := writeFile(t, dir, "script.lua", "local x = 1")
+
+	cases := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{}, exitUsage, "", "usage: tok"},
+		{[]string{"unknown"}, exitUsage, "", `unknown command "unknown"`},
+		{[]string{"parse", valid}, exitOK, "", ""},
+		{[]string{"parse", valid, invalid}, exitInvalid, "", "invalid.json:2:"},
+		{[]string{"parse", data}, exitUsage, "", "no grammar for"},
+		{[]string{"parse", "-g", list, data}, exitOK, "", ""},
+		{[]string{"parse", filepath.Join(dir, "missing.json")}, exitUsage, "", "no such file"},
+		{[]string{"tree", "-g", list, data}, exitOK, "data.txt[0-5)\n  list[0-5) \"[1,2]\"\n", ""},
+		{[]string{"tree", "-json", "-g", list, data}, exitOK, `"text": "[1,2]"`, ""},
+		{[]string{"flame", "-g", list, data}, exitOK, "data.txt[0-5);1.list[0-5) 5\n", ""},
+		{[]string{"trace", "-n", "3", "-g", list, data}, exitOK, "1.@ 0 list > \"[1,\"\n", ""},
+		{[]string{"trace", "-format", "chrome", "-g", list, data}, exitOK, `"traceEvents":[{"name":"list"`, ""},
+		{[]string{"trace", "-axis", "x", "-g", list, data}, exitUsage, "", `unknown axis "x"`},
+		{[]string{"flame", "-format", "speedscope", "-g", list, data}, exitOK, `"unit":"bytes"`, ""},
+		{[]string{"cover", "-html", filepath.Join(dir, "cover.html"), valid, invalid}, exitInvalid, "! 0/1   null: \"null\"\n", "invalid.json:2:"},
+		{[]string{"cover", valid, script}, exitOK, "== lua\n", ""},
+		{[]string{"grammar", list}, exitOK, "list: '[' ?<09> *',' <09> ']'\n", ""},
+		{[]string{"grammar", "json5"}, exitOK, "value: ", ""},
+		{[]string{"grammar", "json"}, exitOK, "value: ", ""},
+		{[]string{"grammar"}, exitUsage, "", "grammar expects one grammar"},
+		{[]string{"gen", "-min", "json"}, exitOK, "0\n", ""},
+		{[]string{"gen", "-rule", "digits", "-min", "json"}, exitOK, "0\n", ""},
+		{[]string{"gen", "-rule", "x", "json"}, exitUsage, "", "unknown rule"},
+		{[]string{"profile", "-pprof", filepath.Join(dir, "list.pprof"), "-g", list, data}, exitOK, "list      1          1", ""},
+		{[]string{"debug", "-b", "list", "-g", list, data}, exitOK, "#1 enter list\n", ""},
+	}
+	for i, c := range cases {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(c.args, strings.NewReader("c\n"), stdout, stderr)
+		if code != c.code {
+			t.Errorf("%d expected exit code %d, got %d: %s", i, c.code, code, stderr)
+		}
+		if !strings.Contains(stdout.String(), c.stdout) {
+			t.Errorf("%d unexpected stdout: %q", i, stdout)
+		}
+		if !strings.Contains(stderr.String(), c.stderr) {
+			t.Errorf("%d unexpected stderr: %q", i, stderr)
+		}
+	}
+}
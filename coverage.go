@@ -0,0 +1,209 @@
+package tok
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"text/tabwriter"
+)
+
+// Hits counts how often a Reader was called and how often it matched.
+type Hits struct {
+	Calls   int
+	Matches int
+}
+
+func (h *Hits) count(err error) {
+	h.Calls++
+	if err == nil {
+		h.Matches++
+	}
+}
+
+// Missed returns true if the Reader never matched.
+func (h Hits) Missed() bool {
+	return h.Matches == 0
+}
+
+func (h Hits) String() string {
+	return fmt.Sprintf("%d/%d", h.Matches, h.Calls)
+}
+
+// AltCoverage contains the Hits of an alternative of an Any Reader in a Rule.
+// Any is the number of the Any Reader in the Rule, Index the number of the alternative, both start with 1.
+type AltCoverage struct {
+	Hits
+	Any   int
+	Index int
+	What  string
+}
+
+// RuleCoverage contains the Hits of a Rule and of the alternatives of the Any Readers in the Rule.
+// Unwalked contains the Readers of the Rule whose alternatives are not covered, see WrapReaders.
+type RuleCoverage struct {
+	Hits
+	Name         string
+	Line         string
+	Alternatives []*AltCoverage
+	Unwalked     []string
+}
+
+// Coverage records across many parses which Rules and which alternatives of Any Readers match.
+type Coverage struct {
+	rules []*RuleCoverage
+}
+
+// CoverGrammar creates a Coverage for all Rules of g.
+func CoverGrammar(g Grammar) *Coverage {
+	c := &Coverage{}
+	c.Cover(g.Grammar()...)
+	return c
+}
+
+// Cover connects the Rules with c.
+func (c *Coverage) Cover(rules ...*Rule) {
+	for _, r := range rules {
+		r.Cover(c)
+	}
+}
+
+func (c *Coverage) add(r *Rule) (*RuleCoverage, Reader) {
+	rc := &RuleCoverage{Name: r.Name, Line: r.Rule()}
+	numAny := 0
+	reader := rc.coverAlternatives(r.Reader, &numAny)
+	c.rules = append(c.rules, rc)
+	return rc, reader
+}
+
+// coverAlternatives returns a copy of r where the alternatives of each Any Reader count their Hits in rc.
+// The Readers of r are not changed, an Any Reader that other Rules share counts for each Rule separately.
+// The copy stops like WrapReaders at Rules, the Readers that it can not walk are stored in the Unwalked field of rc.
+func (rc *RuleCoverage) coverAlternatives(r Reader, numAny *int) Reader {
+	subs := subReaders(r)
+	if !walkable(r) || (len(subs) > 0 && keepsState(r)) {
+		rc.Unwalked = append(rc.Unwalked, r.What())
+		return r
+	}
+	if len(subs) == 0 {
+		return r
+	}
+	_, isAny := r.(*anyReader)
+	if isAny {
+		*numAny++
+	}
+	n := *numAny
+	wrapped := make([]Reader, len(subs))
+	for i, sub := range subs {
+		var ac *AltCoverage
+		if isAny {
+			ac = &AltCoverage{Any: n, Index: i + 1, What: sub.What()}
+			rc.Alternatives = append(rc.Alternatives, ac)
+		}
+		wrapped[i] = rc.coverAlternatives(sub, numAny)
+		if ac != nil {
+			wrapped[i] = &coverReader{&ac.Hits, wrapped[i]}
+		}
+	}
+	c := withSubReaders(r, wrapped)
+	if c == nil {
+		rc.Unwalked = append(rc.Unwalked, r.What())
+		return r
+	}
+	return c
+}
+
+// Rules returns the coverage of each Rule in the order the Rules were connected.
+func (c *Coverage) Rules() []*RuleCoverage {
+	return c.rules
+}
+
+// Missed returns the Rules and alternatives that never matched.
+func (c *Coverage) Missed() []string {
+	res := []string{}
+	for _, rc := range c.rules {
+		if rc.Missed() {
+			res = append(res, rc.Name)
+		}
+		for _, ac := range rc.Alternatives {
+			if ac.Missed() {
+				res = append(res, fmt.Sprintf("%s %d.%d %s", rc.Name, ac.Any, ac.Index, ac.What))
+			}
+		}
+	}
+	return res
+}
+
+func missedMark(h Hits) string {
+	if h.Missed() {
+		return "!"
+	}
+	return ""
+}
+
+// WriteText writes the GrammarLines annotated with the Hits to w.
+// The alternatives follow the Rule with the number of the Any Reader and the alternative.
+// Rules and alternatives that never matched are marked with !, the Unwalked Readers with ?.
+func (c *Coverage) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', 0)
+	for _, rc := range c.rules {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", missedMark(rc.Hits), rc.Hits, rc.Line)
+		for _, ac := range rc.Alternatives {
+			fmt.Fprintf(tw, "%s\t%s\t    %d.%d %s\n", missedMark(ac.Hits), ac.Hits, ac.Any, ac.Index, ac.What)
+		}
+		for _, what := range rc.Unwalked {
+			fmt.Fprintf(tw, "?\t\t    not covered: %s\n", what)
+		}
+	}
+	return tw.Flush()
+}
+
+const coverageStyle = `body { font-family: monospace; }
+.rule { margin-top: 0.5em; }
+.alt { padding-left: 3em; }
+.hits { display: inline-block; width: 8em; text-align: right; padding-right: 1em; }
+.matched { background: #dfd; }
+.missed { background: #fcc; }
+.unwalked { background: #ffc; }
+`
+
+// WriteHTML writes the GrammarLines annotated with the Hits as HTML page to w.
+// Rules and alternatives that never matched are highlighted, the Unwalked Readers are listed like in WriteText.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	class := func(h Hits) string {
+		if h.Missed() {
+			return "missed"
+		}
+		return "matched"
+	}
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>grammar coverage</title>\n<style>\n%s</style>\n</head>\n<body>\n", coverageStyle)
+	for _, rc := range c.rules {
+		fmt.Fprintf(w, "<div class=\"rule %s\"><span class=\"hits\">%s</span>%s</div>\n",
+			class(rc.Hits), rc.Hits, html.EscapeString(rc.Line))
+		for _, ac := range rc.Alternatives {
+			fmt.Fprintf(w, "<div class=\"alt %s\"><span class=\"hits\">%s</span>%d.%d %s</div>\n",
+				class(ac.Hits), ac.Hits, ac.Any, ac.Index, html.EscapeString(ac.What))
+		}
+		for _, what := range rc.Unwalked {
+			fmt.Fprintf(w, "<div class=\"alt unwalked\"><span class=\"hits\">?</span>not covered: %s</div>\n",
+				html.EscapeString(what))
+		}
+	}
+	_, err := fmt.Fprint(w, "</body>\n</html>\n")
+	return err
+}
+
+// ------------------------------------------------------------------------------
+type coverReader struct {
+	hits *Hits
+	sub  Reader
+}
+
+func (r *coverReader) Read(s *Scanner) error {
+	err := r.sub.Read(s)
+	r.hits.count(err)
+	return err
+}
+
+func (r *coverReader) What() string {
+	return r.sub.What()
+}
@@ -0,0 +1,108 @@
+package tok
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	g := newProfileGrammar()
+	c := CoverGrammar(g)
+	for _, text := range []string{"1", "a"} {
+		if err := NewScanner(text).Use(g); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	exp := []string{"list 2/2", "item 2/2", "number 1/2", "name 1/1"}
+	rules := c.Rules()
+	if len(rules) != len(exp) {
+		t.Fatalf("expected %d rules, got %d", len(exp), len(rules))
+	}
+	for i, rc := range rules {
+		if str := rc.Name + " " + rc.Hits.String(); str != exp[i] {
+			t.Errorf("%d expected %q, got %q", i, exp[i], str)
+		}
+	}
+	alts := rules[2].Alternatives
+	if len(alts) != 2 || alts[0].Hits != (Hits{1, 0}) || alts[1].Hits != (Hits{1, 1}) {
+		t.Errorf("unexpected alternatives of number: %v %v", alts[0], alts[1])
+	}
+
+	missed := strings.Join(c.Missed(), "\n")
+	if missed != "number 1.1 @','" {
+		t.Errorf("unexpected missed branches: %q", missed)
+	}
+
+	b := &strings.Builder{}
+	if err := c.WriteText(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), "  1/2 number: ") || !strings.Contains(b.String(), "! 0/1     1.1 @','\n") {
+		t.Errorf("unexpected text report:\n%s", b.String())
+	}
+
+	b.Reset()
+	if err := c.WriteHTML(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), `<div class="alt missed"><span class="hits">0/1</span>1.1 @&#39;,&#39;</div>`) {
+		t.Errorf("unexpected html report:\n%s", b.String())
+	}
+}
+
+func TestCoverageSharedAny(t *testing.T) {
+	sign := Any('+', '-')
+	a := Rule{Name: "a", Reader: Seq('a', sign)}
+	b := Rule{Name: "b", Reader: Seq('b', sign)}
+	c := &Coverage{}
+	c.Cover(&a, &b)
+	for _, text := range []string{"a+", "b-", "b-"} {
+		if err := NewScanner(text).Use(Any(&a, &b)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	exp := "[a 1.1 1/1 a 1.2 0/0 b 1.1 0/2 b 1.2 2/2]"
+	res := []string{}
+	for _, rc := range c.Rules() {
+		for _, ac := range rc.Alternatives {
+			res = append(res, fmt.Sprintf("%s %d.%d %s", rc.Name, ac.Any, ac.Index, ac.Hits))
+		}
+	}
+	if fmt.Sprint(res) != exp {
+		t.Errorf("unexpected alternatives: %v", res)
+	}
+	for _, alt := range sign.(*anyReader).readers {
+		if _, ok := alt.(*coverReader); ok {
+			t.Errorf("the shared Any Reader was changed")
+		}
+	}
+}
+
+func TestCoverageUnwalked(t *testing.T) {
+	list := Rule{Name: "list", Reader: Seq('[', SepBy(Any('a', 'b'), ','), ']')}
+	c := &Coverage{}
+	c.Cover(&list)
+	if err := NewScanner("[a,b]").Use(&list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc := c.Rules()[0]
+	if len(rc.Alternatives) != 0 || fmt.Sprint(rc.Unwalked) != "[*([ 'a' 'b' ] % ',')]" {
+		t.Errorf("unexpected coverage: %v %v", rc.Alternatives, rc.Unwalked)
+	}
+	b := &strings.Builder{}
+	if err := c.WriteText(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), "?         not covered: *([ 'a' 'b' ] % ',')\n") {
+		t.Errorf("unexpected text report:\n%s", b.String())
+	}
+	b.Reset()
+	if err := c.WriteHTML(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), `<div class="alt unwalked"><span class="hits">?</span>not covered: *([ &#39;a&#39; &#39;b&#39; ] % &#39;,&#39;)</div>`) {
+		t.Errorf("unexpected html report:\n%s", b.String())
+	}
+}
@@ -0,0 +1,427 @@
+// Package debug allows to step interactively through the Rules of a Grammar.
+package debug
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aiq/tok"
+)
+
+// ErrQuit is returned by Run if the user quits the Debugger before the parse ends.
+var ErrQuit = errors.New("debug: quit")
+
+const help = `commands:
+  s, step          stops at the next rule enter or exit
+  n, next          steps over the current rule
+  o, out           stops at the exit of the parent rule
+  i, into          stops at the next reader or rule enter or exit
+  v, over          steps over the current reader or rule
+  c, continue      runs to the next breakpoint
+  b, break rule    sets a breakpoint on rule enter
+  d, delete rule   removes a breakpoint
+  breaks           lists the breakpoints
+  t, tail          shows the cursor in the text
+  bt, stack        shows the rule stack
+  p, basket        shows the picked segments
+  back [n]         rewinds n steps
+  goto n           runs the parse again until step n
+  r, restart       runs the parse again from the start
+  q, quit          stops the debugger
+`
+
+type mode int
+
+const (
+	stepMode mode = iota
+	nextMode
+	outMode
+	intoMode
+	overMode
+	continueMode
+)
+
+var modes = map[string]mode{
+	"s": stepMode, "step": stepMode,
+	"n": nextMode, "next": nextMode,
+	"o": outMode, "out": outMode,
+	"i": intoMode, "into": intoMode,
+	"v": overMode, "over": overMode,
+	"c": continueMode, "continue": continueMode,
+}
+
+type frame struct {
+	rule string
+	at   tok.Marker
+}
+
+// event is a Rule or Reader enter or exit, depth counts the Rules and level the Rules and Readers.
+type event struct {
+	n      int
+	enter  bool
+	reader bool
+	rule   string
+	at     tok.Marker
+	depth  int
+	level  int
+	err    error
+}
+
+func (e event) String() string {
+	name := e.rule
+	if e.reader {
+		name = "reader " + name
+	}
+	if e.enter {
+		return fmt.Sprintf("#%d enter %s", e.n, name)
+	}
+	if e.err != nil {
+		return fmt.Sprintf("#%d exit %s failed: %v", e.n, name, e.err)
+	}
+	return fmt.Sprintf("#%d exit %s", e.n, name)
+}
+
+// errStopped is returned by the Readers of the Debugger after a run was aborted.
+var errStopped = errors.New("debug: stopped")
+
+// rewind aborts a run, the next run stops at step target.
+type rewind struct {
+	target int
+}
+
+type quit struct{}
+
+// Debugger runs a Grammar on a Source and stops at Rule enters and exits to read commands.
+type Debugger struct {
+	Breakpoints map[string]bool
+	g           tok.Grammar
+	rules       map[string]bool
+	basket      *tok.Basket
+	in          *bufio.Scanner
+	out         io.Writer
+	unwalked    []string
+
+	src    *tok.Source
+	text   string
+	sca    *tok.Scanner
+	stack  []frame
+	at     tok.Marker
+	n      int
+	mode   mode
+	depth  int
+	level  int
+	nest   int
+	target int
+	stop   interface{}
+	cancel context.CancelFunc
+}
+
+// New creates a Debugger for g that reads the commands from in and writes to out.
+// The Rules of g will be changed to pick the Segments and to stop in the Debugger at the Rules and their Readers.
+func New(g tok.Grammar, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{
+		Breakpoints: map[string]bool{},
+		g:           g,
+		rules:       map[string]bool{},
+		basket:      &tok.Basket{},
+		in:          bufio.NewScanner(in),
+		out:         out,
+	}
+	for _, r := range g.Grammar() {
+		d.rules[r.Name] = true
+		sub, unwalked := tok.WrapReaders(r.Reader, func(sub tok.Reader) tok.Reader {
+			return &stepReader{d, sub.What(), true, sub}
+		})
+		for _, u := range unwalked {
+			d.unwalked = append(d.unwalked, r.Name+": "+u.What())
+		}
+		r.Reader = &stepReader{d, r.Name, false, sub}
+	}
+	d.basket.PickWith(g.Grammar()...)
+	return d
+}
+
+// Unwalked returns the Readers that the Debugger can not step into, each with the name of the Rule, see tok.WrapReaders.
+func (d *Debugger) Unwalked() []string {
+	return d.unwalked
+}
+
+// Break sets breakpoints on the Rules with the names.
+func (d *Debugger) Break(names ...string) error {
+	for _, name := range names {
+		if !d.rules[name] {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+		d.Breakpoints[name] = true
+	}
+	return nil
+}
+
+// Run parses src until the parse ends and the user quits or the commands end.
+// The Debugger stops at the first step if no breakpoint is set.
+// Returns the error of the last parse or ErrQuit if the user quits before the parse ends.
+func (d *Debugger) Run(src *tok.Source) error {
+	d.src = src
+	d.text = src.Text()
+	d.mode = continueMode
+	if len(d.Breakpoints) == 0 {
+		d.mode = stepMode
+	}
+	target := 0
+	for {
+		err := d.run(target)
+		switch v := d.stop.(type) {
+		case rewind:
+			target = v.target
+			continue
+		case quit:
+			return ErrQuit
+		}
+		fmt.Fprintln(d.out, d.result(err))
+		v, ok := d.prompt(nil).(rewind)
+		if !ok {
+			return err
+		}
+		target = v.target
+	}
+}
+
+// run parses the text once, d.stop contains the reason if the run was aborted.
+// An abort cancels the context of the parse, all following Rules and moves of the Scanner fail.
+func (d *Debugger) run(target int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.cancel = cancel
+	d.stop = nil
+	d.sca = tok.NewSourceScanner(d.src)
+	d.basket.Reset()
+	d.sca.Tracker = d.basket
+	d.stack = []frame{}
+	d.at = 0
+	d.n = 0
+	d.nest = 0
+	d.target = target
+	if err := d.sca.UseContext(ctx, d.g); err != nil {
+		return d.src.WrapError(err)
+	}
+	return d.src.WrapError(d.sca.ErrorIfFalse(d.sca.AtEnd(), "end of text"))
+}
+
+func (d *Debugger) result(err error) string {
+	if err != nil {
+		return fmt.Sprintf("#%d end failed: %v", d.n+1, err)
+	}
+	return fmt.Sprintf("#%d end ok", d.n+1)
+}
+
+func (d *Debugger) event(e event) {
+	d.n++
+	e.n = d.n
+	d.at = e.at
+	if d.target > 0 {
+		if d.n < d.target {
+			return
+		}
+		d.target = 0
+	} else {
+		if e.reader && d.mode != intoMode && d.mode != overMode {
+			return
+		}
+		switch d.mode {
+		case nextMode:
+			if e.depth > d.depth {
+				return
+			}
+		case outMode:
+			if e.depth >= d.depth {
+				return
+			}
+		case overMode:
+			if e.level > d.level {
+				return
+			}
+		case continueMode:
+			if !e.enter || e.reader || !d.Breakpoints[e.rule] {
+				return
+			}
+		}
+	}
+	fmt.Fprintln(d.out, e)
+	d.printTail(e.at)
+	if stop := d.prompt(&e); stop != nil {
+		d.stop = stop
+		d.cancel()
+	}
+}
+
+// prompt reads commands until a command resumes the parse, e is nil if the parse has ended.
+// Returns a rewind or quit value if the parse must be aborted, nil to resume.
+func (d *Debugger) prompt(e *event) interface{} {
+	cur := d.n + 1
+	if e != nil {
+		cur = e.n
+	}
+	for {
+		fmt.Fprint(d.out, "(tok) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			d.mode = continueMode
+			d.Breakpoints = map[string]bool{}
+			return nil
+		}
+		args := strings.Fields(d.in.Text())
+		if len(args) == 0 {
+			continue
+		}
+		cmd, arg := args[0], ""
+		if len(args) > 1 {
+			arg = args[1]
+		}
+		switch cmd {
+		case "s", "step", "n", "next", "o", "out", "i", "into", "v", "over", "c", "continue":
+			if e == nil {
+				fmt.Fprintln(d.out, "the parse has ended, use back, goto, restart or quit")
+				continue
+			}
+			d.mode = modes[cmd]
+			d.depth = e.depth
+			d.level = e.level
+			return nil
+		case "back":
+			n, err := d.count(arg, 1)
+			if err != nil {
+				fmt.Fprintln(d.out, err)
+				continue
+			}
+			d.mode = stepMode
+			return rewind{cur - n}
+		case "goto":
+			n, err := d.count(arg, 0)
+			if err != nil || n == 0 {
+				fmt.Fprintln(d.out, "goto expects a step number")
+				continue
+			}
+			d.mode = stepMode
+			return rewind{n}
+		case "r", "restart":
+			d.mode = stepMode
+			return rewind{1}
+		case "q", "quit":
+			return quit{}
+		}
+		d.command(cmd, arg)
+	}
+}
+
+// command executes the commands that don't resume the parse.
+func (d *Debugger) command(cmd string, arg string) {
+	switch cmd {
+	case "b", "break":
+		if err := d.Break(arg); err != nil {
+			fmt.Fprintln(d.out, err)
+		}
+	case "d", "delete":
+		delete(d.Breakpoints, arg)
+	case "breaks":
+		names := []string{}
+		for name := range d.Breakpoints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(d.out, strings.Join(names, " "))
+	case "t", "tail":
+		d.printTail(d.at)
+	case "bt", "stack":
+		for i := len(d.stack) - 1; i >= 0; i-- {
+			f := d.stack[i]
+			fmt.Fprintf(d.out, "%d %s at %s\n", i, f.rule, d.sca.Position(f.at, tok.Runes))
+		}
+	case "p", "basket":
+		for _, seg := range d.basket.Picked() {
+			fmt.Fprintf(d.out, "%s %s\n", seg, strconv.Quote(d.text[seg.From():seg.To()]))
+		}
+	case "h", "help":
+		fmt.Fprint(d.out, help)
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, use help\n", cmd)
+	}
+}
+
+func (d *Debugger) count(arg string, def int) (int, error) {
+	if arg == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", arg)
+	}
+	return n, nil
+}
+
+// printTail prints the line of m with a cursor below the position of m.
+func (d *Debugger) printTail(m tok.Marker) {
+	p := d.sca.Position(m, tok.Runes)
+	start := strings.LastIndexByte(d.text[:m], '\n') + 1
+	end := strings.IndexByte(d.text[m:], '\n')
+	if end < 0 {
+		end = len(d.text)
+	} else {
+		end += int(m)
+	}
+	line := strings.ReplaceAll(d.text[start:end], "\t", " ")
+	prefix := fmt.Sprintf("%s |", p)
+	fmt.Fprintf(d.out, "%s %s\n", prefix, line)
+	fmt.Fprintf(d.out, "%s %s^\n", strings.Repeat(" ", len(prefix)), strings.Repeat(" ", p.Column-1))
+}
+
+// ------------------------------------------------------------------------------
+// stepReader stops the Debugger before and after a Rule or a Reader of a Rule reads.
+type stepReader struct {
+	d      *Debugger
+	name   string
+	reader bool
+	sub    tok.Reader
+}
+
+func (r *stepReader) SubReaders() []tok.Reader {
+	return []tok.Reader{r.sub}
+}
+
+func (r *stepReader) WithSubReaders(subs []tok.Reader) tok.Reader {
+	return &stepReader{r.d, r.name, r.reader, subs[0]}
+}
+
+func (r *stepReader) Read(s *tok.Scanner) error {
+	d := r.d
+	if d.stop != nil {
+		return errStopped
+	}
+	if !r.reader {
+		d.stack = append(d.stack, frame{r.name, s.Mark()})
+		defer func() { d.stack = d.stack[:len(d.stack)-1] }()
+	}
+	d.nest++
+	defer func() { d.nest-- }()
+	e := event{enter: true, reader: r.reader, rule: r.name, at: s.Mark(), depth: len(d.stack), level: d.nest}
+	d.event(e)
+	if d.stop != nil {
+		return errStopped
+	}
+	err := r.sub.Read(s)
+	if d.stop != nil {
+		return errStopped
+	}
+	e.enter, e.at, e.err = false, s.Mark(), err
+	d.event(e)
+	return err
+}
+
+func (r *stepReader) What() string {
+	return r.sub.What()
+}
@@ -0,0 +1,113 @@
+package debug
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+)
+
+const pairGrammar = `pair = key '=' value
+key   = +'a'..'z'
+value = +DIGIT
+`
+
+func runDebugger(t *testing.T, text string, cmds string, breaks ...string) (string, error) {
+	g, err := grammar.Load("pair", pairGrammar)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := &bytes.Buffer{}
+	d := New(g, strings.NewReader(cmds), out)
+	if err := d.Break(breaks...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = d.Run(tok.NewFileSource("x.txt", text))
+	return out.String(), err
+}
+
+func TestDebuggerStep(t *testing.T) {
+	out, err := runDebugger(t, "ab=12", "s\nn\nn\nt\nbt\np\nq\n")
+	if err != ErrQuit {
+		t.Errorf("expected ErrQuit, got %v", err)
+	}
+	exp := []string{
+		"#1 enter pair\nx.txt:1:1 | ab=12\n            ^\n",
+		"#2 enter key\n",
+		"#9 exit key\nx.txt:1:3 | ab=12\n              ^\n",
+		"#12 enter value\n",
+		"(tok) x.txt:1:4 | ab=12\n               ^\n",
+		"(tok) 1 value at x.txt:1:4\n0 pair at x.txt:1:1\n",
+		"(tok) key[0-2) \"ab\"\n",
+	}
+	for _, e := range exp {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected %q in:\n%s", e, out)
+		}
+	}
+}
+
+func TestDebuggerRewind(t *testing.T) {
+	out, err := runDebugger(t, "ab=x", "back 2\ngoto 16\nc\n", "value")
+	if err == nil || err == ErrQuit {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	exp := []string{
+		"#12 enter value\n",
+		"(tok) #10 enter reader '='\n",
+		"(tok) #16 exit pair failed:",
+		"(tok) #17 end failed:",
+	}
+	for _, e := range exp {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected %q in:\n%s", e, out)
+		}
+	}
+}
+
+func TestDebuggerBreak(t *testing.T) {
+	g, _ := grammar.Load("pair", pairGrammar)
+	d := New(g, strings.NewReader(""), &bytes.Buffer{})
+	if err := d.Break("key", "unknown"); err == nil {
+		t.Errorf("expected an error")
+	}
+	out, _ := runDebugger(t, "ab=12", "b value\nbreaks\nc\nq\n")
+	if !strings.Contains(out, "(tok) value\n(tok) #12 enter value\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestDebuggerReaders(t *testing.T) {
+	out, err := runDebugger(t, "ab=12", "i\ni\nv\nv\nq\n")
+	if err != ErrQuit {
+		t.Errorf("expected ErrQuit, got %v", err)
+	}
+	exp := []string{
+		"(tok) #2 enter key\n",
+		"(tok) #3 enter reader <az>\n",
+		"(tok) #4 exit reader <az>\nx.txt:1:2 | ab=12\n             ^\n",
+		"(tok) #5 enter reader <az>\n",
+	}
+	for _, e := range exp {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected %q in:\n%s", e, out)
+		}
+	}
+	out, _ = runDebugger(t, "ab=12", "b value\nc\ni\nv\nv\nq\n")
+	if !strings.Contains(out, "(tok) #13 enter reader <09>\n") || !strings.Contains(out, "(tok) #14 exit reader <09>\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestDebuggerUnwalked(t *testing.T) {
+	g, err := grammar.Load("digits", "digits = {1,3}*DIGIT ';'\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d := New(g, strings.NewReader(""), &bytes.Buffer{})
+	if u := d.Unwalked(); len(u) != 1 || u[0] != "digits: {1,3}*<09>" {
+		t.Errorf("unexpected unwalked Readers: %v", u)
+	}
+}
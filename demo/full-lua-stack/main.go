@@ -20,12 +20,13 @@ func main() {
 		log.Fatalf("not able to read %q: %v", filename, err)
 	}
 
-	sca := tok.NewScanner(string(inp))
+	src := tok.NewFileSource(filename, string(inp))
+	sca := tok.NewSourceScanner(src)
 	lua := grammar.Lua()
 	basket := sca.NewBasketFor(lua)
 	err = sca.Use(lua)
 	if err != nil {
-		log.Fatalf("invalid lua file %q: %v", filename, err)
+		log.Fatalf("invalid lua file: %v", src.WrapError(err))
 	}
 	g := tok.BuildGraph(filename, basket.Picked())
 	fmt.Print(g.FlameStack())
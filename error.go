@@ -1,6 +1,10 @@
 package tok
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Error type that ReadFunc and the Reader here return.
 type ReadError struct {
@@ -18,11 +22,65 @@ func (e ReadError) Error() string {
 	return fmt.Sprintf("not able to read %s at %d", e.What, e.Marker)
 }
 
+// SourceError adds the Position in the File to the message of the ReadError in Err.
+type SourceError struct {
+	Err      error
+	Position Position
+}
+
+// Error function to match the error interface.
+func (e SourceError) Error() string {
+	var re ReadError
+	if !errors.As(e.Err, &re) {
+		return e.Err.Error()
+	}
+	msg := fmt.Sprintf("not able to read %s at %s", re.What, e.Position)
+	return strings.Replace(e.Err.Error(), re.Error(), msg, 1)
+}
+
+// Unwrap returns Err.
+func (e SourceError) Unwrap() error {
+	return e.Err
+}
+
+// LimitError is the error that a Scanner returns if a safety limit was exceeded.
+type LimitError struct {
+	Marker
+	Limit string
+	Value int
+}
+
+// Error function to match the error interface.
+func (e LimitError) Error() string {
+	return fmt.Sprintf("exceeded %s limit of %d at %d", e.Limit, e.Value, e.Marker)
+}
+
+// ContextError is the error that a Scanner returns if the context of UseContext is done.
+type ContextError struct {
+	Marker
+	Err error
+}
+
+// Error function to match the error interface.
+func (e ContextError) Error() string {
+	return fmt.Sprintf("stopped reading at %d: %v", e.Marker, e.Err)
+}
+
+// Unwrap returns the error of the context.
+func (e ContextError) Unwrap() error {
+	return e.Err
+}
+
 // Generates a ReadError for name.
 func (s *Scanner) ErrorFor(name string) error {
 	return ReadError{s.Mark(), name}
 }
 
+// Generates a ReadError for name at the Marker m.
+func (s *Scanner) ErrorAt(m Marker, name string) error {
+	return ReadError{m, name}
+}
+
 // Generates a ErrorFor if ok is false, otherwise returns the function nil.
 func (s *Scanner) ErrorIfFalse(ok bool, name string) error {
 	if !ok {
@@ -0,0 +1,709 @@
+package tok
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// infCost is the cost of a Reader that can't produce a text.
+const infCost = 1 << 30
+
+// genTries is the number of texts that Generate creates to find one that passes the lookaheads.
+const genTries = 100
+
+// Generator creates random texts that a Reader can read.
+// Each random decision is a choice, the choices of the last text allow Shrink to search smaller texts.
+// Lookaheads like At, AtEnd or To produce no text of their own, the Generator checks them on the complete text.
+// To inserts a text of its sub Reader if the following text doesn't pass, Past and To write no runes that the sub Reader reads.
+type Generator struct {
+	// MaxDepth limits the nesting of Rules, deeper Rules produce their shortest text.
+	MaxDepth int
+	// MaxRepeat limits the number of optional items of Zom, Many, Repeat and SepBy.
+	MaxRepeat int
+	rnd       *rand.Rand
+	text      string
+	choices   []int
+	trace     []int
+	replay    []int
+	next      int
+	depth     int
+	janus     map[*janusEndReader]string
+	ends      []*janusEndReader
+	checks    []lookCheck
+	costs     map[*Rule]int
+}
+
+// lookCheck is a lookahead that the complete text must pass at pos.
+// fix is the text that To inserts if the check fails, janus stores the Janus strings at pos.
+type lookCheck struct {
+	pos   int
+	r     Reader
+	fix   string
+	janus map[*janusEndReader]string
+}
+
+// lookaheadError reports a generated text that fails a lookahead or that the Reader can't read.
+type lookaheadError struct {
+	what string
+	text string
+}
+
+func (e lookaheadError) Error() string {
+	return fmt.Sprintf("generated text %q fails %s", e.text, e.what)
+}
+
+// NewGenerator creates a Generator that uses seed for the random decisions.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		MaxDepth:  16,
+		MaxRepeat: 4,
+		rnd:       rand.New(rand.NewSource(seed)),
+		costs:     map[*Rule]int{},
+	}
+}
+
+// Generate creates a random text for r.
+// The Generator uses the first Rule of a Grammar.
+// A text that fails a lookahead or that r can't read is dropped, Generate returns an error after genTries dropped texts.
+func (g *Generator) Generate(r Reader) (string, error) {
+	var err error
+	for i := 0; i < genTries; i++ {
+		var text string
+		var choices []int
+		text, choices, err = g.run(r, nil)
+		if err == nil {
+			g.text, g.choices = text, choices
+			return text, nil
+		}
+		if _, ok := err.(lookaheadError); !ok {
+			return "", err
+		}
+	}
+	return "", err
+}
+
+// Shrink searches with fewer and smaller choices for a smaller text than the last generated one that keep accepts.
+// keep must accept the last generated text, a keep function that accepts all texts leads to the shortest text of r.
+func (g *Generator) Shrink(r Reader, keep func(string) bool) (string, error) {
+	if g.choices == nil {
+		return "", fmt.Errorf("no generated text to shrink")
+	}
+	best := g.choices
+	try := func(choices []int) bool {
+		text, used, err := g.run(r, choices)
+		if err != nil || !smaller(text, used, g.text, best) || !keep(text) {
+			return false
+		}
+		g.text, g.choices, best = text, used, used
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		for k := 8; k > 0; k-- {
+			for i := 0; i+k <= len(best); {
+				cand := append(append([]int{}, best[:i]...), best[i+k:]...)
+				if try(cand) {
+					changed = true
+				} else {
+					i++
+				}
+			}
+		}
+		for i := 0; i < len(best); i++ {
+			for _, v := range []int{0, best[i] / 2, best[i] - 1} {
+				if i >= len(best) || v < 0 || v >= best[i] {
+					continue
+				}
+				cand := append([]int{}, best...)
+				cand[i] = v
+				if try(cand) {
+					changed = true
+				}
+			}
+		}
+	}
+	return g.text, nil
+}
+
+// smaller returns true if text a is shorter than text b, texts with the same length are compared via the choices.
+func smaller(a string, ac []int, b string, bc []int) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	if len(ac) != len(bc) {
+		return len(ac) < len(bc)
+	}
+	for i := range ac {
+		if ac[i] != bc[i] {
+			return ac[i] < bc[i]
+		}
+	}
+	return false
+}
+
+// run generates a text for r, the choices are taken from replay if it is not nil.
+func (g *Generator) run(r Reader, replay []int) (string, []int, error) {
+	if gr, ok := r.(Grammar); ok {
+		if _, isRule := r.(*Rule); !isRule {
+			rules := gr.Grammar()
+			if len(rules) == 0 {
+				return "", nil, fmt.Errorf("grammar %s has no rules", r.What())
+			}
+			r = rules[0]
+		}
+	}
+	rules := usedRules(r)
+	g.updateCosts(rules)
+	g.ends = janusEnds(r, rules)
+	g.trace = []int{}
+	g.replay, g.next = replay, 0
+	g.depth = 0
+	g.janus = map[*janusEndReader]string{}
+	g.checks = nil
+	b := &strings.Builder{}
+	if err := g.gen(b, r); err != nil {
+		return "", g.trace, err
+	}
+	text, err := g.check(b.String())
+	if err != nil {
+		return "", g.trace, err
+	}
+	s := NewScanner(text)
+	if err := s.Use(r); err != nil || !s.AtEnd() {
+		return "", g.trace, lookaheadError{r.What(), text}
+	}
+	return text, g.trace, nil
+}
+
+// check runs the lookaheads on the complete text, from the last one to the first one.
+// A failed To check inserts the text of the sub Reader, this moves only the positions of checks that already passed.
+func (g *Generator) check(text string) (string, error) {
+	for i := len(g.checks) - 1; i >= 0; i-- {
+		c := g.checks[i]
+		if g.readsAt(text, c.pos, c.pos+1, c.r, c.janus) {
+			continue
+		}
+		if c.fix != "" {
+			text = text[:c.pos] + c.fix + text[c.pos:]
+			if g.readsAt(text, c.pos, c.pos+1, c.r, c.janus) {
+				continue
+			}
+		}
+		return "", lookaheadError{c.r.What(), text}
+	}
+	return text, nil
+}
+
+// readsAt returns true if r reads text at a position >= from and < to.
+// The Janus Readers expect the strings of janus.
+func (g *Generator) readsAt(text string, from int, to int, r Reader, janus map[*janusEndReader]string) bool {
+	old := make([]string, len(g.ends))
+	for i, end := range g.ends {
+		old[i] = end.reader.str
+		end.reader.str = janus[end]
+	}
+	defer func() {
+		for i, end := range g.ends {
+			end.reader.str = old[i]
+		}
+	}()
+	s := NewScanner(text)
+	for m := from; m < to && m <= len(text); m++ {
+		s.ToMarker(Marker(m))
+		if s.Use(r) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// copyJanus returns a copy of the Janus strings that the Generator expects.
+func (g *Generator) copyJanus() map[*janusEndReader]string {
+	res := make(map[*janusEndReader]string, len(g.janus))
+	for k, v := range g.janus {
+		res[k] = v
+	}
+	return res
+}
+
+// choose returns a value >= 0 and < n.
+// Deeper than MaxDepth the choice is always 0.
+func (g *Generator) choose(n int) int {
+	if n <= 1 || g.depth > g.MaxDepth {
+		return 0
+	}
+	c := 0
+	if g.replay != nil {
+		if g.next < len(g.replay) {
+			c = g.replay[g.next] % n
+			g.next++
+		}
+	} else {
+		c = g.rnd.Intn(n)
+	}
+	g.trace = append(g.trace, c)
+	return c
+}
+
+// more decides if a repetition with n items and at most max items gets another item.
+// A negative max value allows MaxRepeat optional items.
+// Each optional item has its own choice, this allows Shrink to remove an item with its choices.
+func (g *Generator) more(n int, min int, max int) bool {
+	if n < min {
+		return true
+	}
+	if max < 0 || max-min > g.MaxRepeat {
+		max = min + g.MaxRepeat
+	}
+	return n < max && g.choose(2) == 1
+}
+
+func (g *Generator) gen(b *strings.Builder, r Reader) error {
+	switch v := r.(type) {
+	case *Rule:
+		g.depth++
+		err := g.gen(b, v.Reader)
+		g.depth--
+		return err
+	case litReader:
+		b.WriteString(v.str)
+	case *foldReader:
+		for _, c := range v.val {
+			if g.choose(2) == 1 {
+				c = unicode.SimpleFold(c)
+			}
+			b.WriteRune(c)
+		}
+	case runeReader:
+		b.WriteRune(v.r)
+	case *anyRuneReader:
+		runes := []rune(v.str)
+		if len(runes) == 0 {
+			return g.unable(r)
+		}
+		b.WriteRune(runes[g.choose(len(runes))])
+	case betweenReader:
+		n := validRunes(v.min, v.max)
+		if n == 0 {
+			return g.unable(r)
+		}
+		b.WriteRune(nthRune(v.min, g.choose(n)))
+	case *betweenAnyReader:
+		singles := []rune(v.singles)
+		i := g.choose(len(v.min) + len(singles))
+		if i >= len(v.min) {
+			b.WriteRune(singles[i-len(v.min)])
+			return nil
+		}
+		n := validRunes(v.min[i], v.max[i])
+		if n == 0 {
+			return g.unable(r)
+		}
+		b.WriteRune(nthRune(v.min[i], g.choose(n)))
+	case holeyReader:
+		n := validRunes(v.min, v.max)
+		start := g.choose(n)
+		for i := 0; i < n; i++ {
+			c := nthRune(v.min, (start+i)%n)
+			if !strings.ContainsRune(v.holes, c) {
+				b.WriteRune(c)
+				return nil
+			}
+		}
+		return g.unable(r)
+	case *BoolReader:
+		values := []string{"true", "false", "True", "False", "TRUE", "FALSE"}
+		switch v.Format {
+		case "l":
+			values = values[0:2]
+		case "Cc":
+			values = values[2:4]
+		case "U":
+			values = values[4:6]
+		}
+		b.WriteString(values[g.choose(len(values))])
+	case *IntReader:
+		if !validBase(v.Base) {
+			return g.unable(r)
+		}
+		val := int64(g.choose(maxIntValue(v.BitSize - 1)))
+		if g.choose(2) == 1 {
+			val = -val
+		}
+		b.WriteString(strconv.FormatInt(val, v.Base))
+	case *UintReader:
+		if !validBase(v.Base) {
+			return g.unable(r)
+		}
+		b.WriteString(strconv.FormatUint(uint64(g.choose(maxIntValue(v.BitSize))), v.Base))
+	case *anyReader:
+		readers := g.byCost(v.readers)
+		return g.gen(b, readers[g.choose(len(readers))])
+	case *seqReader:
+		for _, sub := range v.readers {
+			if err := g.gen(b, sub); err != nil {
+				return err
+			}
+		}
+	case *skipSeqReader:
+		if err := g.gen(b, v.skip); err != nil {
+			return err
+		}
+		for _, sub := range v.readers {
+			if err := g.gen(b, sub); err != nil {
+				return err
+			}
+			if err := g.gen(b, v.skip); err != nil {
+				return err
+			}
+		}
+	case *manyReader:
+		return g.genRepeat(b, v.sub, 1, -1)
+	case *zomReader:
+		return g.genRepeat(b, v.sub, 0, -1)
+	case *optReader:
+		return g.genRepeat(b, v.sub, 0, 1)
+	case *timesReader:
+		return g.genRepeat(b, v.sub, v.n, v.n)
+	case *RepeatReader:
+		return g.genRepeat(b, v.sub, v.Min, v.Max)
+	case *SepByReader:
+		n := 0
+		for ; g.more(n, v.Min, -1); n++ {
+			if n > 0 {
+				if err := g.gen(b, v.sep); err != nil {
+					return err
+				}
+			}
+			if err := g.gen(b, v.item); err != nil {
+				return err
+			}
+		}
+		if v.Trail && n > 0 && g.choose(2) == 1 {
+			return g.gen(b, v.sep)
+		}
+	case *janusBeginReader:
+		m := b.Len()
+		if err := g.gen(b, v.reader); err != nil {
+			return err
+		}
+		g.janus[v.end] = b.String()[m:]
+	case *janusEndReader:
+		b.WriteString(g.janus[v])
+		delete(g.janus, v)
+	case matchReader:
+		c, ok := g.chooseRune('\t', '~', v.f)
+		if !ok {
+			return g.unable(r)
+		}
+		b.WriteRune(c)
+	case *notReader:
+		return g.genNot(b, v)
+	case *pastReader:
+		return g.genPast(b, v)
+	case *toReader:
+		return g.genTo(b, v)
+	case *bodyReader:
+		return g.gen(b, v.body)
+	case *bodyTailReader:
+		if err := g.gen(b, v.body); err != nil {
+			return err
+		}
+		return g.gen(b, v.tail)
+	case *atReader, atEndReader, *behindReader, *notBehindReader:
+		g.checks = append(g.checks, lookCheck{b.Len(), r, "", g.copyJanus()})
+	case *revReader:
+		return g.gen(b, v.sub)
+	case *mapReader:
+		return g.gen(b, v.sub)
+	case *namedReader:
+		return g.gen(b, v.sub)
+	case *ruleNameReader:
+		return g.gen(b, v.sub)
+	case *monitorReader:
+		return g.gen(b, v.sub)
+	case *pickReader:
+		return g.gen(b, v.sub)
+	case *profileReader:
+		return g.gen(b, v.sub)
+	case *coverReader:
+		return g.gen(b, v.sub)
+	default:
+		return g.unable(r)
+	}
+	return nil
+}
+
+func (g *Generator) genRepeat(b *strings.Builder, r Reader, min int, max int) error {
+	for n := 0; g.more(n, min, max); n++ {
+		if err := g.gen(b, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genNot writes a printable ASCII rune that the sub Reader of r doesn't match.
+func (g *Generator) genNot(b *strings.Builder, r *notReader) error {
+	c, ok := g.chooseRune(' ', '~', func(c rune) bool {
+		return NewScanner(string(c)).Use(r.sub) != nil
+	})
+	if !ok {
+		return g.unable(r)
+	}
+	b.WriteRune(c)
+	return nil
+}
+
+// genPast writes runes that the sub Reader of r doesn't read and a text of the sub Reader.
+func (g *Generator) genPast(b *strings.Builder, r *pastReader) error {
+	janus := g.copyJanus()
+	sub := &strings.Builder{}
+	if err := g.gen(sub, r.sub); err != nil {
+		return err
+	}
+	g.genFill(b, r.sub, sub.String(), janus)
+	b.WriteString(sub.String())
+	return nil
+}
+
+// genTo writes runes that the sub Reader of r doesn't read, the following text must pass the sub Reader.
+// The text of the sub Reader is generated with a copy of the Janus strings, it is the fix of the check.
+func (g *Generator) genTo(b *strings.Builder, r *toReader) error {
+	janus := g.copyJanus()
+	sub := &strings.Builder{}
+	err := g.gen(sub, r.sub)
+	g.janus = janus
+	if err != nil {
+		return err
+	}
+	g.genFill(b, r.sub, sub.String(), janus)
+	g.checks = append(g.checks, lookCheck{b.Len(), r.sub, sub.String(), g.copyJanus()})
+	return nil
+}
+
+// genFill writes printable ASCII runes where r reads nothing, also if next follows the runes.
+func (g *Generator) genFill(b *strings.Builder, r Reader, next string, janus map[*janusEndReader]string) {
+	text, from := b.String(), b.Len()
+	fill := ""
+	for n := 0; g.more(n, 0, -1); n++ {
+		c, ok := g.chooseRune(' ', '~', func(c rune) bool {
+			cand := text + fill + string(c)
+			return !g.readsAt(cand+next, from, len(cand), r, janus)
+		})
+		if !ok {
+			break
+		}
+		fill += string(c)
+	}
+	b.WriteString(fill)
+}
+
+// chooseRune returns a rune >= min and <= max that passes check, the search starts at a chosen rune.
+func (g *Generator) chooseRune(min rune, max rune, check MatchFunc) (rune, bool) {
+	n := int(max-min) + 1
+	start := g.choose(n)
+	for i := 0; i < n; i++ {
+		c := min + rune((start+i)%n)
+		if check(c) {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// The surrogates have no UTF-8 encoding, the Generator skips them in rune ranges.
+const (
+	surrogateMin = 0xD800
+	surrogateMax = 0xDFFF
+)
+
+// validRunes returns the number of runes >= min and <= max without the surrogates.
+func validRunes(min rune, max rune) int {
+	if max < min {
+		return 0
+	}
+	n := int(max-min) + 1
+	lo, hi := min, max
+	if lo < surrogateMin {
+		lo = surrogateMin
+	}
+	if hi > surrogateMax {
+		hi = surrogateMax
+	}
+	if lo <= hi {
+		n -= int(hi-lo) + 1
+	}
+	return n
+}
+
+// nthRune returns the rune with the index i in the range that starts at min, the surrogates are skipped.
+func nthRune(min rune, i int) rune {
+	if min >= surrogateMin && min <= surrogateMax {
+		min = surrogateMax + 1
+	}
+	c := min + rune(i)
+	if min < surrogateMin && c >= surrogateMin {
+		c += surrogateMax - surrogateMin + 1
+	}
+	return c
+}
+
+func (g *Generator) unable(r Reader) error {
+	return fmt.Errorf("can't generate a text for %s", r.What())
+}
+
+func validBase(base int) bool {
+	return base == 8 || base == 10 || base == 16
+}
+
+// maxIntValue returns the number of values that the generated integers can have.
+func maxIntValue(bits int) int {
+	if bits > 16 {
+		bits = 16
+	}
+	return 1 << uint(bits)
+}
+
+// ------------------------------------------------------------------------------
+// byCost returns readers sorted by the length of the shortest text, this makes choice 0 the smallest one.
+func (g *Generator) byCost(readers []Reader) []Reader {
+	res := append([]Reader{}, readers...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return g.cost(res[i]) < g.cost(res[j])
+	})
+	return res
+}
+
+// usedRules returns all Rules that r uses.
+func usedRules(r Reader) []*Rule {
+	rules := []*Rule{}
+	seen := map[*Rule]bool{}
+	var collect func(r Reader)
+	collect = func(r Reader) {
+		walkReaders(r, func(sub Reader) {
+			rule, ok := sub.(*Rule)
+			if !ok || seen[rule] {
+				return
+			}
+			seen[rule] = true
+			rules = append(rules, rule)
+			collect(rule.Reader)
+		})
+	}
+	collect(r)
+	return rules
+}
+
+// janusEnds returns the ends of the Janus pairs that r and rules use.
+func janusEnds(r Reader, rules []*Rule) []*janusEndReader {
+	ends := []*janusEndReader{}
+	collect := func(r Reader) {
+		if end, ok := r.(*janusEndReader); ok {
+			ends = append(ends, end)
+		}
+	}
+	walkReaders(r, collect)
+	for _, rule := range rules {
+		walkReaders(rule.Reader, collect)
+	}
+	return ends
+}
+
+// updateCosts calculates the costs of rules until the costs don't change.
+func (g *Generator) updateCosts(rules []*Rule) {
+	for _, rule := range rules {
+		if _, ok := g.costs[rule]; !ok {
+			g.costs[rule] = infCost
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range rules {
+			if c := g.cost(rule.Reader); c < g.costs[rule] {
+				g.costs[rule] = c
+				changed = true
+			}
+		}
+	}
+}
+
+func addCost(a int, b int) int {
+	if a+b > infCost {
+		return infCost
+	}
+	return a + b
+}
+
+// cost estimates the length of the shortest text for r.
+func (g *Generator) cost(r Reader) int {
+	switch v := r.(type) {
+	case *Rule:
+		if c, ok := g.costs[v]; ok {
+			return c
+		}
+		return infCost
+	case litReader:
+		return len(v.str)
+	case *foldReader:
+		return len(v.val)
+	case runeReader, *anyRuneReader, betweenReader, *betweenAnyReader, holeyReader, *notReader, matchReader:
+		return 1
+	case *BoolReader:
+		return 4
+	case *IntReader, *UintReader:
+		return 1
+	case *anyReader:
+		min := infCost
+		for _, sub := range v.readers {
+			if c := g.cost(sub); c < min {
+				min = c
+			}
+		}
+		return min
+	case *seqReader:
+		sum := 0
+		for _, sub := range v.readers {
+			sum = addCost(sum, g.cost(sub))
+		}
+		return sum
+	case *skipSeqReader:
+		sum := g.cost(v.skip)
+		for _, sub := range v.readers {
+			sum = addCost(sum, addCost(g.cost(sub), g.cost(v.skip)))
+		}
+		return sum
+	case *zomReader, *optReader, *janusEndReader, *atReader, atEndReader, *behindReader, *notBehindReader, *toReader:
+		return 0
+	case *timesReader:
+		return g.timesCost(v.sub, v.n)
+	case *RepeatReader:
+		return g.timesCost(v.sub, v.Min)
+	case *SepByReader:
+		if v.Min == 0 {
+			return 0
+		}
+		return g.cost(v.item)
+	case *bodyTailReader:
+		return addCost(g.cost(v.body), g.cost(v.tail))
+	case *bodyReader:
+		return g.cost(v.body)
+	}
+	subs := subReaders(r)
+	if len(subs) != 1 {
+		return infCost
+	}
+	return g.cost(subs[0])
+}
+
+func (g *Generator) timesCost(r Reader, n int) int {
+	sum := 0
+	for i := 0; i < n; i++ {
+		sum = addCost(sum, g.cost(r))
+	}
+	return sum
+}
@@ -0,0 +1,148 @@
+package tok
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// newGenRules creates Rules for nested lists of numbers, words and tags like <b>x</b>.
+func newGenRules() *Rule {
+	list := &Rule{Name: "list"}
+	value := &Rule{Name: "value"}
+	number := &Rule{Name: "number", Reader: Many(Digit())}
+	word := &Rule{Name: "word", Reader: Seq(Between('a', 'z'), Times(2, AnyRune("xyz")))}
+	beg, end := Janus("tag", Many(Between('a', 'c')))
+	tag := &Rule{Name: "tag", Reader: Seq('<', beg, '>', Opt(value), "</", end, '>')}
+	flag := &Rule{Name: "flag", Reader: Seq(Fold("on"), Not(Rune(']')))}
+	list.Reader = Seq('[', SepBy(value, Rune(',')), ']')
+	value.Reader = Any(list, tag, flag, word, number)
+	return list
+}
+
+func TestGenerate(t *testing.T) {
+	list := newGenRules()
+	for seed := int64(1); seed <= 50; seed++ {
+		gen := NewGenerator(seed)
+		gen.MaxDepth = 6
+		text, err := gen.Generate(list)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", seed, err)
+		}
+		s := NewScanner(text)
+		if err := s.Use(list); err != nil || !s.AtEnd() {
+			t.Errorf("%d generated invalid text %q: %v", seed, text, err)
+		}
+		again := NewGenerator(seed)
+		again.MaxDepth = 6
+		if same, _ := again.Generate(list); same != text {
+			t.Errorf("%d expected the same text for the same seed", seed)
+		}
+	}
+}
+
+func TestGenerateMaxDepth(t *testing.T) {
+	list := newGenRules()
+	gen := NewGenerator(1)
+	gen.MaxDepth = 0
+	gen.MaxRepeat = 100
+	text, err := gen.Generate(list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "[]" {
+		t.Errorf("expected the shortest text, got %q", text)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	gen := NewGenerator(1)
+	if _, err := gen.Generate(Wrap("x", func(s *Scanner) error { return nil })); err == nil {
+		t.Errorf("expected an error for a Wrap Reader")
+	}
+	if _, err := gen.Shrink(Lit("x"), func(string) bool { return true }); err == nil {
+		t.Errorf("expected an error without generated text")
+	}
+}
+
+func TestGenerateLookaheads(t *testing.T) {
+	cases := []Reader{
+		Seq("//", To(Any('\n', AtEnd())), Zom(AnyRune(" \n")), 'x'),
+		Seq("/*", Past("*/"), Opt('x')),
+		Seq(Many(Digit()), At(Not(Digit())), Opt(' ')),
+		Seq(Zom(Match("space", unicode.IsSpace)), 'a', AtEnd()),
+		Seq(Opt('a'), Behind(Rune('a')), Opt('b')),
+	}
+	for i, r := range cases {
+		gen := NewGenerator(int64(i))
+		for j := 0; j < 50; j++ {
+			text, err := gen.Generate(r)
+			if err != nil {
+				t.Fatalf("%d.%d unexpected error: %v", i, j, err)
+			}
+			s := NewScanner(text)
+			if err := s.Use(r); err != nil || !s.AtEnd() {
+				t.Errorf("%d.%d the Reader can't read %q: %v", i, j, text, err)
+			}
+		}
+	}
+	if _, err := NewGenerator(1).Generate(Seq(At(Rune('b')), 'a')); err == nil {
+		t.Errorf("expected an error for a lookahead that no text passes")
+	}
+}
+
+func TestGenerateSkipsSurrogates(t *testing.T) {
+	cases := []Reader{
+		Between(0xD7FF, 0xE000),
+		BuildBetweenAny(0xD800, 0xE001),
+		Holey(0xD700, 0xE000, "\uD7FF"),
+		Many(Between(0, 0x10FFFF)),
+	}
+	for i, r := range cases {
+		gen := NewGenerator(int64(i))
+		for j := 0; j < 50; j++ {
+			text, err := gen.Generate(r)
+			if err != nil {
+				t.Fatalf("%d unexpected error: %v", i, err)
+			}
+			if !utf8.ValidString(text) || strings.ContainsRune(text, utf8.RuneError) {
+				t.Errorf("%d.%d generated a surrogate: %q", i, j, text)
+			}
+			if err := NewScanner(text).Use(r); err != nil {
+				t.Errorf("%d.%d the Reader can't read %q: %v", i, j, text, err)
+			}
+		}
+	}
+	if _, err := NewGenerator(1).Generate(Between(0xD800, 0xDFFF)); err == nil {
+		t.Errorf("expected an error for a range of surrogates")
+	}
+}
+
+func TestShrink(t *testing.T) {
+	list := newGenRules()
+	hasTag := func(text string) bool {
+		return strings.Contains(text, "</")
+	}
+	for seed := int64(1); seed <= 50; seed++ {
+		gen := NewGenerator(seed)
+		text, err := gen.Generate(list)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", seed, err)
+		}
+		if !hasTag(text) {
+			continue
+		}
+		min, err := gen.Shrink(list, hasTag)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", seed, err)
+		}
+		if min != "[<a></a>]" {
+			t.Errorf("%d expected [<a></a>] for %q, got %q", seed, text, min)
+		}
+		all, _ := gen.Shrink(list, func(string) bool { return true })
+		if all != "[]" {
+			t.Errorf("%d expected [], got %q", seed, all)
+		}
+	}
+}
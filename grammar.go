@@ -3,6 +3,7 @@ package tok
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 //------------------------------------------------------------------------------
@@ -25,53 +26,117 @@ type Grammar interface {
 }
 
 // SetRuleNames sets the rule names via the associated Field-Tag.
-func SetRuleNames(g Grammar) error {
-	t := reflect.TypeOf(g).Elem()
-	v := reflect.ValueOf(g).Elem()
+// g is a pointer to a Grammar or to a module, a struct with Rule and Template fields.
+// The Rules of an exported embedded Grammar get their names too, a derived Grammar can therefore embed the Grammar it extends.
+// The Rules of an exported Grammar or module field with a Field-Tag get the tag as namespace, like json.string.
+// Returns an error if g is not a pointer to a struct.
+func SetRuleNames(g interface{}) error {
+	v, ok := structOf(g)
+	if !ok {
+		return fmt.Errorf("SetRuleNames expects a pointer to a struct, got %T", g)
+	}
+	return setRuleNames(v, "")
+}
+
+// structOf returns the struct that g points to.
+func structOf(g interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(g)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return v, false
+	}
+	return v.Elem(), true
+}
+
+func setRuleNames(v reflect.Value, prefix string) error {
+	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if field.Type != reflect.TypeOf(Rule{}) {
+		name := field.Tag.Get("name")
+		if name != "" {
+			if e := CheckRuleName(name); e != nil {
+				return fmt.Errorf("invalid name for %s: %v", field.Name, e)
+			}
+		}
+		if sub, ns, ok := subModule(field, v.Field(i)); ok {
+			if err := setRuleNames(sub, prefix+ns); err != nil {
+				return err
+			}
 			continue
 		}
-		name := field.Tag.Get("name")
 		if name == "" {
 			continue
 		}
-		if e := CheckRuleName(name); e != nil {
-			return fmt.Errorf("invalid name for %s: %v", field.Name, e)
+		switch field.Type {
+		case reflect.TypeOf(Rule{}):
+			rule := v.Field(i)
+			ruleName := rule.FieldByName("Name")
+			ruleName.Set(reflect.ValueOf(prefix + name))
+		case reflect.TypeOf(Template{}):
+			v.Field(i).Addr().Interface().(*Template).Name = prefix + name
 		}
-		rule := v.Field(i)
-		ruleName := rule.FieldByName("Name")
-		ruleName.Set(reflect.ValueOf(name))
 	}
 	return nil
 }
 
+// subModule returns the struct of an exported field that can contain Rules, with the namespace of its Rules.
+// An embedded field shares the namespace, a named field needs a Field-Tag for its namespace.
+func subModule(field reflect.StructField, v reflect.Value) (reflect.Value, string, bool) {
+	if field.PkgPath != "" || field.Type == reflect.TypeOf(Rule{}) || field.Type == reflect.TypeOf(Template{}) {
+		return v, "", false
+	}
+	ns := ""
+	if !field.Anonymous {
+		name := field.Tag.Get("name")
+		if name == "" {
+			return v, "", false
+		}
+		ns = name + "."
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, "", false
+		}
+		v = v.Elem()
+	}
+	return v, ns, v.Kind() == reflect.Struct
+}
+
 // MustSetRuleNames panics if an error occurs during SetRuleNames.
-func MustSetRuleNames(g Grammar) {
+func MustSetRuleNames(g interface{}) {
 	err := SetRuleNames(g)
 	if err != nil {
 		panic(err)
 	}
 }
 
-// CollectRules collects the Rules in a Grammar.
-func CollectRules(g Grammar) []*Rule {
-	rules := []*Rule{}
-	v := reflect.ValueOf(g).Elem()
+// CollectRules collects the Rules in a Grammar or module.
+// The Rules of an exported embedded or imported Grammar are collected at the position of the field.
+// Returns no Rules if g is not a pointer to a struct.
+func CollectRules(g interface{}) []*Rule {
+	v, ok := structOf(g)
+	if !ok {
+		return []*Rule{}
+	}
+	return collectRules([]*Rule{}, v)
+}
+
+func collectRules(rules []*Rule, v reflect.Value) []*Rule {
+	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
-		i := field.Addr().Interface()
-		ptr, ok := i.(*Rule)
-		if !ok {
+		if sub, _, ok := subModule(t.Field(i), field); ok {
+			rules = collectRules(rules, sub)
 			continue
 		}
-		rules = append(rules, ptr)
+		if field.Type() == reflect.TypeOf(Rule{}) {
+			rules = append(rules, field.Addr().Interface().(*Rule))
+		}
 	}
 	return rules
 }
 
-// CheckRules checks if the Rules in a Grammar have a Name and a Reader set.
+// CheckRules checks if the Rules in a Grammar have a Name and a valid Reader set.
+// A Reader is invalid if it uses an InvalidReader or a RepeatReader with a Min greater than a not negative Max.
 func CheckRules(g Grammar) error {
 	for _, r := range g.Grammar() {
 		if e := CheckRuleName(r.Name); e != nil {
@@ -80,10 +145,32 @@ func CheckRules(g Grammar) error {
 		if r.Reader == nil {
 			return fmt.Errorf("the Reader of %s is a nil value", r.Name)
 		}
+		if e := checkReader(r.Reader); e != nil {
+			return fmt.Errorf("the Reader of %s is invalid: %w", r.Name, e)
+		}
 	}
 	return nil
 }
 
+// checkReader returns the error of the first invalid Reader that r uses.
+func checkReader(r Reader) error {
+	var err error
+	walkReaders(r, func(sub Reader) {
+		if err != nil {
+			return
+		}
+		switch v := sub.(type) {
+		case invalidReader:
+			err = v.err
+		case *RepeatReader:
+			if v.Max >= 0 && v.Min > v.Max {
+				err = fmt.Errorf("min %d is greater than max %d", v.Min, v.Max)
+			}
+		}
+	})
+	return err
+}
+
 // MustCheckRules panics if an error occurs during CheckRules.
 func MustCheckRules(g Grammar) {
 	err := CheckRules(g)
@@ -92,6 +179,36 @@ func MustCheckRules(g Grammar) {
 	}
 }
 
+// Override replaces the Reader of the Rule with the name in g by r.
+// A derived Grammar embeds the Grammar it extends and shares therefore its Rules, nothing is copied.
+// All Readers that use the Rule read afterwards with r, also the Rules of the embedded Grammar.
+// A Reader that was copied from the Rule keeps the old one.
+// Override the Rules before calling Pick, Map or Monitor on them, r replaces those wrappers too.
+// Returns an error if g has no Rule with the name, if more than one Rule has the name,
+// like a Rule of the derived Grammar with the name of an embedded Rule, or if CheckRules fails afterwards.
+// The Rule keeps its Reader if an error occurs.
+func Override(g Grammar, name string, r Reader) error {
+	found := false
+	for _, rule := range g.Grammar() {
+		if rule.Name == name && found {
+			return fmt.Errorf("the name %s is used by more than one rule", name)
+		}
+		found = found || rule.Name == name
+	}
+	for _, rule := range g.Grammar() {
+		if rule.Name == name {
+			old := rule.Reader
+			rule.Reader = r
+			if err := CheckRules(g); err != nil {
+				rule.Reader = old
+				return err
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no rule %s", g.What(), name)
+}
+
 // ------------------------------------------------------------------------------
 // RuleReader can be used to set the rules of a grammar.
 type Rule struct {
@@ -108,12 +225,35 @@ func (r *Rule) Monitor(l *Log) {
 	r.Reader = Monitor(r.Reader, l, r.Name)
 }
 
+// Profile reports the calls of the Rule to p.
+// p records the backtracked bytes only as Tracker of the Scanner.
+func (r *Rule) Profile(p *Profiler) {
+	r.Reader = Profile(r.Reader, p, r.Name)
+}
+
+// Cover records the Hits of the Rule and of the alternatives of its Any Readers in c.
+// The Rule reads afterwards with a copy of its Reader, Readers that other Rules share are not changed.
+func (r *Rule) Cover(c *Coverage) {
+	rc, reader := c.add(r)
+	r.Reader = &coverReader{&rc.Hits, reader}
+}
+
 // Pick collects the Segments if a Reader was moven and sets the Info field with the Reader Name.
 func (r *Rule) Pick(basket *Basket) {
 	r.Reader = Pick(r.Reader, basket, r.Name)
 }
 
 func (r *Rule) Read(s *Scanner) error {
+	if s.rules == nil && s.limits == nil {
+		return r.Reader.Read(s)
+	}
+	if c, ok := s.rules[r]; ok {
+		r = c
+	}
+	defer s.exitRule()
+	if err := s.enterRule(); err != nil {
+		return err
+	}
 	return r.Reader.Read(s)
 }
 
@@ -125,6 +265,39 @@ func (r *Rule) Rule() string {
 	return fmt.Sprintf("%s: %s", r.Name, r.Reader.What())
 }
 
+// ------------------------------------------------------------------------------
+// Template is a parameterized Rule, Apply creates the Reader that New creates for the arguments.
+// A Grammar names an application with a Rule of its own, like g.Items.Reader = g.Blocks.List.Apply(&g.Item, ','),
+// the Template keeps no state and can be shared by several Grammars.
+// SetRuleNames sets the Name of a Template field.
+type Template struct {
+	Name   string
+	Params []string
+	New    func(args ...Reader) Reader
+}
+
+// Apply creates a new Reader for the arguments, the type of an argument can be rune, string or Reader.
+// Returns an invalid Reader if the number of arguments does not match the Params.
+func (t *Template) Apply(args ...interface{}) Reader {
+	readers := make([]Reader, len(args))
+	for i, arg := range args {
+		sub, ok := asReader(arg)
+		if !ok {
+			return InvalidReader("invalid %s parameter at %d: unknown type %T", t.What(), i+1, arg)
+		}
+		readers[i] = sub
+	}
+	if len(args) != len(t.Params) {
+		return InvalidReader("invalid %s arguments: expects (%s), got %d", t.What(), strings.Join(t.Params, ","), len(args))
+	}
+	return t.New(readers...)
+}
+
+// What returns the Name with the Params, like list(item,sep).
+func (t *Template) What() string {
+	return fmt.Sprintf("%s(%s)", t.Name, strings.Join(t.Params, ","))
+}
+
 // ------------------------------------------------------------------------------
 type ruleNameReader struct {
 	sub Reader
@@ -0,0 +1,48 @@
+package grammar
+
+import (
+	"unicode/utf8"
+
+	. "github.com/aiq/tok"
+)
+
+// BlocksModule has parameterized rules for recurring building blocks of grammars.
+// A Grammar imports the module with a named field and names the applications with its own Rules:
+//
+//	type CSVReader struct {
+//		Blocks *grammar.BlocksModule `name:"blocks"`
+//		Line   Rule                  `name:"line"`
+//	}
+//
+//	g.Line.Reader = g.Blocks.List.Apply(&g.Field, ',')
+type BlocksModule struct {
+	List   Template `name:"list"`
+	Quoted Template `name:"quoted"`
+	Block  Template `name:"block"`
+}
+
+// Blocks creates a BlocksModule with the following Templates:
+//
+//	list(item,sep)          one or more items separated by sep
+//	quoted(quote)           text between quote runes, a backslash escapes the next rune
+//	block(open,body,close)  body between open and close, with optional whitespaces inside
+func Blocks() *BlocksModule {
+	m := &BlocksModule{}
+	SetRuleNames(m)
+	m.List.Params = []string{"item", "sep"}
+	m.List.New = func(args ...Reader) Reader {
+		return Seq(args[0], Zom(Seq(args[1], args[0])))
+	}
+	m.Quoted.Params = []string{"quote"}
+	m.Quoted.New = func(args ...Reader) Reader {
+		quote := args[0]
+		escape := Seq('\\', Between(0, utf8.MaxRune))
+		return Seq(quote, Zom(Any(escape, Not(Any(quote, '\\', AtEnd())))), quote)
+	}
+	m.Block.Params = []string{"open", "body", "close"}
+	m.Block.New = func(args ...Reader) Reader {
+		ws := Zom(WS())
+		return Seq(args[0], ws, args[1], ws, args[2])
+	}
+	return m
+}
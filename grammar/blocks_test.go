@@ -0,0 +1,90 @@
+package grammar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+)
+
+type settingsReader struct {
+	Blocks   *BlocksModule `name:"blocks"`
+	JSON     *JSONReader   `name:"json"`
+	Quoted   tok.Rule      `name:"quoted"`
+	Name     tok.Rule      `name:"name"`
+	Setting  tok.Rule      `name:"setting"`
+	List     tok.Rule      `name:"list"`
+	Settings tok.Rule      `name:"settings"`
+}
+
+// newSettingsReader creates a Grammar for a block like { name = "value"; other = [1, 2] }
+func newSettingsReader(blocks *BlocksModule) *settingsReader {
+	g := &settingsReader{Blocks: blocks, JSON: JSON()}
+	tok.MustSetRuleNames(g)
+	g.Quoted.Reader = g.Blocks.Quoted.Apply('\'')
+	g.Name.Reader = tok.Any(&g.Quoted, tok.Many(tok.BetweenAny("a-z")))
+	g.Setting.Reader = tok.Seq(&g.Name, tok.Zom(' '), '=', &g.JSON.Element)
+	g.List.Reader = g.Blocks.List.Apply(&g.Setting, tok.Seq(';', tok.Zom(' ')))
+	g.Settings.Reader = g.Blocks.Block.Apply('{', &g.List, '}')
+	return g
+}
+
+func (g *settingsReader) Read(s *tok.Scanner) error {
+	return g.Settings.Read(s)
+}
+
+func (g *settingsReader) What() string {
+	return "settings"
+}
+
+func (g *settingsReader) Grammar() []*tok.Rule {
+	return tok.CollectRules(g)
+}
+
+func TestBlocks(t *testing.T) {
+	blocks := Blocks()
+	g := newSettingsReader(blocks)
+	other := newSettingsReader(blocks)
+	for _, sg := range []*settingsReader{g, other} {
+		if err := tok.CheckRules(sg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names := []string{}
+		for _, r := range sg.Grammar() {
+			if !strings.HasPrefix(r.Name, "json.") {
+				names = append(names, r.Name)
+			}
+		}
+		exp := "quoted name setting list settings"
+		if strings.Join(names, " ") != exp {
+			t.Errorf("unexpected rules: %v", names)
+		}
+	}
+	if rules := tok.CollectRules(blocks); len(rules) != 0 {
+		t.Errorf("the shared module should have no rules: %v", rules)
+	}
+	if blocks.List.What() != "blocks.list(item,sep)" {
+		t.Errorf("unexpected template: %s", blocks.List.What())
+	}
+	if g.JSON.String.Name != "json.string" {
+		t.Errorf("unexpected rule name: %s", g.JSON.String.Name)
+	}
+	sca := tok.NewScanner(`{ name = "tok"; 'a \' b' ={"x": [1, 2]} }`)
+	basket := sca.NewBasket()
+	basket.PickWith(&g.Name, &g.JSON.String)
+	if err := sca.Use(g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sca.AtEnd() {
+		t.Errorf("did not read the whole text: %q", sca.Tail())
+	}
+	if basket.String() != "name[2-6);json.string[9-14);name[16-24)" {
+		t.Errorf("unexpected picks: %s", basket.String())
+	}
+	for i, c := range []string{`{}`, `{ a = 1; }`, `{ 'a = 1 }`, `{ a = 1 b = 2 }`} {
+		sca := tok.NewScanner(c)
+		if sca.Use(g) == nil && sca.AtEnd() {
+			t.Errorf("%d expected an error for %q", i, c)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package grammar
+
+// The cases of the tests are the seeds of the fuzz tests.
+var (
+	JSONCases = jsonCases
+	LuaCases  = luaCases
+	MXTCases  = mxtSeeds()
+)
+
+func mxtSeeds() []string {
+	seeds := []string{}
+	for _, c := range mxtCases {
+		seeds = append(seeds, c.mxt)
+	}
+	return seeds
+}
@@ -0,0 +1,24 @@
+//go:build go1.18
+// +build go1.18
+
+package grammar_test
+
+import (
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+	"github.com/aiq/tok/toktest"
+)
+
+func FuzzJSON(f *testing.F) {
+	toktest.Fuzz(f, func() tok.Grammar { return grammar.JSON() }, grammar.JSONCases...)
+}
+
+func FuzzLua(f *testing.F) {
+	toktest.Fuzz(f, func() tok.Grammar { return grammar.Lua() }, grammar.LuaCases...)
+}
+
+func FuzzMXT(f *testing.F) {
+	toktest.Fuzz(f, func() tok.Grammar { return grammar.MXT() }, grammar.MXTCases...)
+}
@@ -0,0 +1,16 @@
+package grammar_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+	"github.com/aiq/tok/toktest"
+)
+
+var update = flag.Bool("update", false, "rewrites the golden files")
+
+func TestJSONGolden(t *testing.T) {
+	toktest.Golden(t, "testdata/json.mxt", func() tok.Grammar { return grammar.JSON() })
+}
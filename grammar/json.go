@@ -32,12 +32,16 @@ type JSONReader struct {
 	Bool       Rule `name:"bool"`
 	Null       Rule `name:"null"`
 	WS         Rule `name:"ws"`
+
+	name string
 }
 
 // JSON creates a Grammar to Read a JSON file.
 // The implementation is based on https://www.crockford.com/mckeeman.html
+// The key rule reads with the Reader of the string rule, the Graph has the characters node directly below
+// the key node and a derived Grammar that overrides string overrides key too.
 func JSON() *JSONReader {
-	g := &JSONReader{}
+	g := &JSONReader{name: "json"}
 	SetRuleNames(g)
 	g.WS.Reader = Zom(WS())
 	g.Null.Reader = Lit("null")
@@ -69,13 +73,13 @@ func JSON() *JSONReader {
 func (r *JSONReader) Read(s *Scanner) error {
 	err := r.Element.Read(s)
 	if err != nil {
-		return fmt.Errorf("json parse error: %v", err)
+		return fmt.Errorf("%s parse error: %w", r.name, err)
 	}
 	return nil
 }
 
 func (r *JSONReader) What() string {
-	return "json"
+	return r.name
 }
 
 func (r *JSONReader) Grammar() []*Rule {
@@ -0,0 +1,97 @@
+package grammar
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	. "github.com/aiq/tok"
+)
+
+// JSONCReader extends the JSON Grammar with comments and trailing commas.
+type JSONCReader struct {
+	*JSONReader
+	Comment Rule `name:"comment"`
+}
+
+// JSONC creates a Grammar to Read JSON with comments and trailing commas.
+// JSONC overrides the WS, Elements and Members rules of the JSON Grammar.
+func JSONC() *JSONCReader {
+	g := &JSONCReader{JSONReader: JSON()}
+	g.name = "jsonc"
+	SetRuleNames(g)
+	g.Comment.Reader = jsonComment()
+	g.WS.Reader = Zom(Any(WS(), &g.Comment))
+	g.Elements.Reader = Seq(&g.Element, Zom(Seq(',', &g.Element)), Opt(Seq(',', &g.WS)))
+	g.Members.Reader = Seq(&g.Member, Zom(Seq(',', &g.Member)), Opt(Seq(',', &g.WS)))
+	return g
+}
+
+func (r *JSONCReader) Grammar() []*Rule {
+	return CollectRules(r)
+}
+
+// JSON5Reader extends the JSONC Grammar to a JSON5 Grammar.
+type JSON5Reader struct {
+	*JSONCReader
+	Identifier Rule `name:"identifier"`
+}
+
+// JSON5 creates a Grammar to Read a JSON5 file.
+// JSON5 extends the JSONC Grammar with identifiers as keys, single quoted and multi-line strings,
+// hex numbers, Infinity and NaN.
+// The implementation is based on https://spec.json5.org/
+func JSON5() *JSON5Reader {
+	g := &JSON5Reader{JSONCReader: JSONC()}
+	g.name = "json5"
+	SetRuleNames(g)
+	g.WS.Reader = Zom(Any(Match("whitespace", isJSON5Space), &g.Comment))
+	g.Integer.Reader = Any(Rune('0'), Seq(&g.OneNine, Opt(&g.Digits)))
+	g.Fraction.Reader = Opt(Seq('.', Opt(&g.Digits)))
+	hex := Seq('0', AnyRune("xX"), Many(&g.Hex))
+	decimal := Any(Seq(&g.Integer, &g.Fraction, &g.Exponent), Seq('.', &g.Digits, &g.Exponent))
+	g.Number.Reader = Seq(Opt(AnyRune("+-")), Any("Infinity", "NaN", hex, decimal))
+	lineEnd := Any("\r\n", AnyRune("\n\r\u2028\u2029"))
+	g.Escape.Reader = Any(
+		Seq('u', Times(4, &g.Hex)),
+		Seq('x', Times(2, &g.Hex)),
+		Seq('0', At(Not(Digit()))),
+		lineEnd,
+		Holey(0, utf8.MaxRune, "0123456789xu\n\r\u2028\u2029"),
+	)
+	g.Character.Reader = Any(Holey(0, utf8.MaxRune, "\"'\\\n\r"), Seq('\\', &g.Escape))
+	g.String.Reader = Any(
+		Seq('"', Zom(Any(&g.Character, '\'')), '"'),
+		Seq('\'', Zom(Any(&g.Character, '"')), '\''),
+	)
+	unicodeEscape := Seq(`\u`, Times(4, &g.Hex))
+	idStart := Any(Match("identifier start", isJSON5IDStart), unicodeEscape)
+	idPart := Any(Match("identifier part", isJSON5IDPart), unicodeEscape)
+	g.Identifier.Reader = Seq(idStart, Zom(idPart))
+	g.Key.Reader = Any(g.String.Reader, &g.Identifier)
+	return g
+}
+
+func (r *JSON5Reader) Grammar() []*Rule {
+	return CollectRules(r)
+}
+
+// jsonComment returns a Reader for the line and block comments of JSONC and JSON5.
+func jsonComment() Reader {
+	line := Seq("//", To(Any('\n', AtEnd())))
+	block := Seq("/*", Past("*/"))
+	return Any(line, block)
+}
+
+func isJSON5Space(r rune) bool {
+	return unicode.Is(unicode.Zs, r) || strings.ContainsRune("\t\n\v\f\r\u2028\u2029\ufeff", r)
+}
+
+func isJSON5IDStart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.Is(unicode.Nl, r) || r == '$' || r == '_'
+}
+
+func isJSON5IDPart(r rune) bool {
+	return isJSON5IDStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) ||
+		r == '\u200c' || r == '\u200d'
+}
@@ -0,0 +1,182 @@
+package grammar
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aiq/tok"
+)
+
+func checkJSONReader(t *testing.T, newGrammar func() tok.Grammar, valid []string, invalid []string) {
+	t.Helper()
+	for i, c := range valid {
+		sca := tok.NewScanner(c)
+		if err := sca.Use(newGrammar()); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		} else if !sca.AtEnd() {
+			t.Errorf("%d did not read the whole text: %q", i, sca.Tail())
+		}
+	}
+	for i, c := range invalid {
+		sca := tok.NewScanner(c)
+		if sca.Use(newGrammar()) == nil && sca.AtEnd() {
+			t.Errorf("%d expected an error for %q", i, c)
+		}
+	}
+}
+
+func TestJSONC(t *testing.T) {
+	valid := append([]string{
+		`// settings
+		{
+			/* the name */ "name": "tok", // trailing
+			"list": [1, 2, 3,],
+		}`,
+		`[] // no line break at the end`,
+		`{"a": /* ** */ 1}`,
+	}, jsonCases...)
+	invalid := []string{
+		`[1,,]`,
+		`[,]`,
+		`{,}`,
+		`{"a": 1 /* open }`,
+		`{a: 1}`,
+		`'single'`,
+	}
+	checkJSONReader(t, func() tok.Grammar { return JSONC() }, valid, invalid)
+	if err := tok.CheckRules(JSONC()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestJSON5(t *testing.T) {
+	valid := append([]string{
+		`// https://spec.json5.org/#introduction
+		{
+			// comments
+			unquoted: 'and you can quote me on that',
+			singleQuotes: 'I can use "double quotes" here',
+			lineBreaks: "Look, Mom! \
+No \\n's!",
+			hexadecimal: 0xdecaf,
+			leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+			positiveSign: +1,
+			trailingComma: 'in objects', andIn: ['arrays',],
+			"backwardsCompatible": "with JSON",
+		}`,
+		`[Infinity, -Infinity, NaN, +NaN, 1e+3, -0x1F]`,
+		`{$_ünïcode\u0041: '\x41\0\'\v\q'}`,
+		"{\u00a0a:\ufeff1}",
+	}, jsonCases...)
+	invalid := []string{
+		`{1a: 1}`,
+		`'\1'`,
+		`"\01"`,
+		`"line
+break"`,
+		`01`,
+		`0x`,
+		`[.]`,
+		`infinity`,
+	}
+	checkJSONReader(t, func() tok.Grammar { return JSON5() }, valid, invalid)
+	if err := tok.CheckRules(JSON5()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestJSONCGrammar(t *testing.T) {
+	g := JSON5()
+	names := []string{}
+	for _, r := range g.Grammar() {
+		names = append(names, r.Name)
+	}
+	if len(names) != len(JSON().Grammar())+2 {
+		t.Errorf("unexpected rules: %v", names)
+	}
+	if n := len(names); names[n-2] != "comment" || names[n-1] != "identifier" {
+		t.Errorf("unexpected rules: %v", names)
+	}
+	sca := tok.NewScanner(`{a: 1} // end`)
+	basket := sca.NewBasket()
+	basket.PickWith(&g.Comment, &g.Identifier)
+	if err := sca.Use(g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if basket.String() != "identifier[1-2);comment[7-13)" {
+		t.Errorf("unexpected picks: %s", basket.String())
+	}
+}
+
+func TestJSONRuleOverride(t *testing.T) {
+	sca := tok.NewScanner(`[1, 2,]`)
+	if err := sca.Use(JSON()); err == nil && sca.AtEnd() {
+		t.Errorf("JSON should not accept trailing commas")
+	}
+	err := tok.NewScanner(`[1,,]`).Use(JSONC())
+	if err == nil || err.Error() != "jsonc parse error: not able to read ']' at 3" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	backquoted := tok.Seq('`', tok.Zom(tok.Holey(0, utf8.MaxRune, "`")), '`')
+	g := JSON()
+	if err := tok.Override(g, "string", backquoted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tok.NewScanner("{\"a b\": `c`}").Use(g); err != nil {
+		t.Errorf("the key should keep its reader: %v", err)
+	}
+	if err := tok.NewScanner("{`a b`: `c`}").Use(g); err == nil {
+		t.Errorf("the key should not use the overridden string")
+	}
+	if err := tok.Override(g, "key", backquoted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tok.NewScanner("{`a b`: `c`}").Use(g); err != nil {
+		t.Errorf("the key should use the overridden key: %v", err)
+	}
+}
+
+// identJSON derives from JSON via embedding, it shares the Rules of the embedded JSONReader.
+type identJSON struct {
+	*JSONReader
+	Ident tok.Rule `name:"ident"`
+}
+
+func (g *identJSON) Grammar() []*tok.Rule {
+	return tok.CollectRules(g)
+}
+
+func TestDeriveJSON(t *testing.T) {
+	base := JSON()
+	g := &identJSON{JSONReader: base}
+	tok.MustSetRuleNames(g)
+	g.Ident.Reader = tok.Many(tok.Between('a', 'z'))
+	if err := tok.Override(g, "key", tok.Any(&g.String, &g.Ident)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules := g.Grammar()
+	for i, r := range base.Grammar() {
+		if rules[i] != r {
+			t.Errorf("%d the rule %s was copied", i, r.Name)
+		}
+	}
+	if rules[len(rules)-1] != &g.Ident {
+		t.Errorf("unexpected last rule: %s", rules[len(rules)-1].Name)
+	}
+
+	sca := tok.NewScanner(`{a: [1, {"b": {c: 2}}]}`)
+	basket := sca.NewBasket()
+	basket.PickWith(&g.Key, &g.Ident)
+	if err := sca.Use(g); err != nil || !sca.AtEnd() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if basket.String() != "ident[1-2);key[1-2);key[9-12);ident[15-16);key[15-16)" {
+		t.Errorf("unexpected picks: %s", basket.String())
+	}
+	if err := tok.NewScanner(`{a: 1}`).Use(base); err != nil {
+		t.Errorf("the embedded grammar should read with the overridden key: %v", err)
+	}
+	if err := tok.NewScanner(`{a: 1}`).Use(JSON()); err == nil {
+		t.Errorf("a new JSON grammar should not read identifiers")
+	}
+}
@@ -1,51 +1,53 @@
 package grammar
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/aiq/tok"
 )
 
-func TestJSON(t *testing.T) {
-
-	posCases := []string{
-		`{}`,
-		`{"key":"value"}`,
-		`{
-			"glossary": {
-				"title": "example glossary",
-				"GlossDiv": {
-					"title": "S",
-					"GlossList": {
-						"GlossEntry": {
-							"ID": "SGML",
-							"SortAs": "SGML",
-							"GlossTerm": "Standard Generalized Markup Language",
-							"Acronym": "SGML",
-							"Abbrev": "ISO 8879:1986",
-							"GlossDef": {
-								"para": "A meta-markup language, used to create markup languages such as DocBook.",
-								"GlossSeeAlso": ["GML", "XML"]
-							},
-							"GlossSee": "markup"
-						}
+var jsonCases = []string{
+	`{}`,
+	`{"key":"value"}`,
+	`{
+		"glossary": {
+			"title": "example glossary",
+			"GlossDiv": {
+				"title": "S",
+				"GlossList": {
+					"GlossEntry": {
+						"ID": "SGML",
+						"SortAs": "SGML",
+						"GlossTerm": "Standard Generalized Markup Language",
+						"Acronym": "SGML",
+						"Abbrev": "ISO 8879:1986",
+						"GlossDef": {
+							"para": "A meta-markup language, used to create markup languages such as DocBook.",
+							"GlossSeeAlso": ["GML", "XML"]
+						},
+						"GlossSee": "markup"
 					}
 				}
 			}
-		}`,
-		`{"menu": {
-			"id": "file",
-			"value": "File",
-			"popup": {
-			  "menuitem": [
-				{"value": "New", "onclick": "CreateNewDoc()"},
-				{"value": "Open", "onclick": "OpenDoc()"},
-				{"value": "Close", "onclick": "CloseDoc()"}
-			  ]
-			}
-		  }}`,
-	}
-	for i, c := range posCases {
+		}
+	}`,
+	`{"menu": {
+		"id": "file",
+		"value": "File",
+		"popup": {
+		  "menuitem": [
+			{"value": "New", "onclick": "CreateNewDoc()"},
+			{"value": "Open", "onclick": "OpenDoc()"},
+			{"value": "Close", "onclick": "CloseDoc()"}
+		  ]
+		}
+	  }}`,
+}
+
+func TestJSON(t *testing.T) {
+	for i, c := range jsonCases {
 		sca := tok.NewScanner(c)
 		err := sca.Use(JSON())
 		if err != nil {
@@ -56,3 +58,95 @@ func TestJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONLimits(t *testing.T) {
+	inp := strings.Repeat("[", 100000) + strings.Repeat("]", 100000)
+	sca := tok.NewScanner(inp)
+	sca.SetLimits(tok.Limits{MaxDepth: 5000})
+	err := sca.Use(JSON())
+	if _, ok := err.(tok.LimitError); !ok {
+		t.Errorf("expected a LimitError: %v", err)
+	}
+	if !sca.AtStart() {
+		t.Errorf("scanner was not restored")
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	g := JSON()
+	for seed := int64(1); seed <= 20; seed++ {
+		gen := tok.NewGenerator(seed)
+		gen.MaxDepth = 8
+		text, err := gen.Generate(g)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", seed, err)
+		}
+		if !json.Valid([]byte(text)) {
+			t.Errorf("%d generated invalid json: %q", seed, text)
+		}
+		sca := tok.NewScanner(text)
+		if err := sca.Use(g); err != nil || !sca.AtEnd() {
+			t.Errorf("%d grammar can't read generated json %q: %v", seed, text, err)
+		}
+	}
+}
+
+func TestGenerateGrammars(t *testing.T) {
+	cases := []struct {
+		g       tok.Grammar
+		lookFor string
+	}{
+		{Lua(), "--"},
+		{MXT(), "-->"},
+		{JSONC(), "//"},
+		{JSON5(), "/*"},
+	}
+	for _, c := range cases {
+		found := false
+		for seed := int64(1); seed <= 50; seed++ {
+			gen := tok.NewGenerator(seed)
+			gen.MaxDepth = 8
+			text, err := gen.Generate(c.g)
+			if err != nil {
+				t.Fatalf("%s %d unexpected error: %v", c.g.What(), seed, err)
+			}
+			sca := tok.NewScanner(text)
+			if err := sca.Use(c.g); err != nil || !sca.AtEnd() {
+				t.Errorf("%s %d grammar can't read generated text %q: %v", c.g.What(), seed, text, err)
+			}
+			found = found || strings.Contains(text, c.lookFor)
+		}
+		if !found {
+			t.Errorf("%s expected a text with %q", c.g.What(), c.lookFor)
+		}
+	}
+}
+
+func TestJSONIncremental(t *testing.T) {
+	inp := `{"a": [1, 2, {"b": true}], "c": "text", "d": null}`
+	edits := []tok.Edit{
+		tok.MakeEdit(7, 8, "10"),
+		tok.MakeEdit(20, 24, "false"),
+		tok.MakeEdit(16, 17, "bb"),
+		tok.MakeEdit(34, 38, `"more text"`),
+		tok.MakeEdit(7, 9, `"x"`),
+		tok.MakeEdit(1, 4, `"e"`),
+		tok.MakeEdit(6, 6, "[] , "),
+	}
+	inc := tok.NewIncremental("json", JSON())
+	if _, err := inc.Parse(inp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, e := range edits {
+		inp, _ = e.Apply(inp)
+		g, _, err := inc.Edit(e)
+		exp, expErr := tok.NewIncremental("json", JSON()).Parse(inp)
+		if (err == nil) != (expErr == nil) {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if err == nil && !g.Equal(exp) {
+			t.Errorf("%d unexpected graph:\n%s!=\n%s", i, g.FlameStack(), exp.FlameStack())
+		}
+	}
+}
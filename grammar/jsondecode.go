@@ -0,0 +1,611 @@
+package grammar
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	. "github.com/aiq/tok"
+)
+
+// JSONObject is a decoded JSON object that keeps the order of its members.
+type JSONObject []JSONMember
+
+// JSONMember is a key value pair of a JSONObject.
+type JSONMember struct {
+	Key   string
+	Value interface{}
+}
+
+// Get returns the value of the last member with key.
+func (o JSONObject) Get(key string) (interface{}, bool) {
+	for i := len(o) - 1; i >= 0; i-- {
+		if o[i].Key == key {
+			return o[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// JSONTypeError describes a JSON value that can't be stored in a Go value of a specific type.
+// Err is the error of an encoding.TextUnmarshaler that rejected a key, otherwise nil.
+type JSONTypeError struct {
+	Position Position
+	Value    string
+	Type     reflect.Type
+	Err      error
+}
+
+// Error function to match the error interface.
+func (e *JSONTypeError) Error() string {
+	msg := fmt.Sprintf("json: can't decode %s at %s into Go value of type %s", e.Value, e.Position, e.Type)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns Err.
+func (e *JSONTypeError) Unwrap() error {
+	return e.Err
+}
+
+// ------------------------------------------------------------------------------
+// JSONDecoder decodes JSON texts with the JSON Grammar.
+// The decoder parses the whole text with the Grammar and picks the values, it is therefore much slower than encoding/json.
+// A JSONDecoder can be reused, but not concurrently.
+// Decode supports json.Unmarshaler, encoding.TextUnmarshaler and the string option of the json tags.
+type JSONDecoder struct {
+	// KeepOrder decodes objects as JSONObject instead of map[string]interface{}.
+	KeepOrder bool
+	// UseNumber decodes numbers as json.Number instead of float64.
+	UseNumber bool
+
+	g       *JSONReader
+	tracker *furthestTracker
+	sca     *Scanner
+	// quoted decodes the values of fields with the string option
+	quoted *JSONDecoder
+}
+
+// NewJSONDecoder creates a JSONDecoder with a JSON Grammar that picks the values.
+func NewJSONDecoder() *JSONDecoder {
+	g := JSON()
+	basket := &Basket{}
+	basket.PickWith(&g.Object, &g.Member, &g.Key, &g.Array, &g.String, &g.Number, &g.Bool, &g.Null)
+	return &JSONDecoder{g: g, tracker: &furthestTracker{Basket: basket}}
+}
+
+// furthestTracker is a Basket that tracks the furthest Marker the Scanner reached.
+type furthestTracker struct {
+	*Basket
+	furthest Marker
+}
+
+func (t *furthestTracker) Update(m Marker) {
+	if m > t.furthest {
+		t.furthest = m
+	}
+	t.Basket.Update(m)
+}
+
+// jsonNode is a picked value of the JSON text with the picked sub values.
+type jsonNode struct {
+	Segment
+	nodes []*jsonNode
+}
+
+// parse reads text and returns the root of the picked values.
+func (d *JSONDecoder) parse(text string) (*jsonNode, error) {
+	d.tracker.Reset()
+	d.tracker.furthest = 0
+	d.sca = NewSourceScanner(NewFileSource("", text))
+	d.sca.Tracker = d.tracker
+	if err := d.sca.Use(&d.g.Element); err != nil {
+		return nil, d.parseError(err)
+	}
+	if err := d.sca.ErrorIfFalse(d.sca.AtEnd(), "end of text"); err != nil {
+		return nil, d.parseError(err)
+	}
+	// the segments of the sub values are picked before the segment of the value
+	stack := []*jsonNode{}
+	for _, seg := range d.tracker.Picked() {
+		n := &jsonNode{Segment: seg}
+		i := len(stack)
+		for i > 0 && seg.Covers(stack[i-1].Token) {
+			i--
+		}
+		n.nodes = append(n.nodes, stack[i:]...)
+		stack = append(stack[:i], n)
+	}
+	return stack[0], nil
+}
+
+// parseError moves the ReadError err to the furthest Marker the Scanner reached.
+// The readers report the error where the last alternative failed, mostly at the start of the value.
+func (d *JSONDecoder) parseError(err error) error {
+	var re ReadError
+	if furthest := d.tracker.furthest; errors.As(err, &re) && furthest > re.Marker {
+		d.sca.ToMarker(furthest)
+		what := "end of text"
+		if !d.sca.AtEnd() {
+			r, _ := utf8.DecodeRuneInString(d.sca.Tail())
+			what = strconv.QuoteRune(r)
+		}
+		err = d.sca.ErrorAt(furthest, what)
+	}
+	return fmt.Errorf("json parse error: %w", d.sca.Source().WrapError(err))
+}
+
+// Value decodes text into nil, bool, float64, string, []interface{} and map[string]interface{} values.
+func (d *JSONDecoder) Value(text string) (interface{}, error) {
+	n, err := d.parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return d.value(n)
+}
+
+// Decode decodes text into the value that v points to, like json.Unmarshal.
+// Types that implement json.Unmarshaler get the raw text of their value.
+func (d *JSONDecoder) Decode(text string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	n, err := d.parse(text)
+	if err != nil {
+		return err
+	}
+	return d.decode(n, rv.Elem())
+}
+
+// DecodeJSON decodes text into Go values like JSONDecoder.Value.
+// It parses the whole text with the Grammar before it decodes a value and is an order of magnitude
+// slower than json.Unmarshal, BenchmarkDecodeJSON and BenchmarkEncodingJSON compare both.
+func DecodeJSON(text string) (interface{}, error) {
+	return NewJSONDecoder().Value(text)
+}
+
+// DecodeJSONInto decodes text into the value that v points to like JSONDecoder.Decode.
+// It is an order of magnitude slower than json.Unmarshal, BenchmarkDecodeJSONInto and BenchmarkEncodingJSONInto compare both.
+func DecodeJSONInto(text string, v interface{}) error {
+	return NewJSONDecoder().Decode(text, v)
+}
+
+// ------------------------------------------------------------------------------
+
+// value returns the Go value of n, a number that is out of the float64 range is a JSONTypeError.
+func (d *JSONDecoder) value(n *jsonNode) (interface{}, error) {
+	switch n.Info {
+	case "object":
+		if d.KeepOrder {
+			obj := make(JSONObject, 0, len(n.nodes))
+			for _, m := range n.nodes {
+				v, err := d.value(m.nodes[1])
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, JSONMember{d.text(m.nodes[0]), v})
+			}
+			return obj, nil
+		}
+		obj := make(map[string]interface{}, len(n.nodes))
+		for _, m := range n.nodes {
+			v, err := d.value(m.nodes[1])
+			if err != nil {
+				return nil, err
+			}
+			obj[d.text(m.nodes[0])] = v
+		}
+		return obj, nil
+	case "array":
+		arr := make([]interface{}, 0, len(n.nodes))
+		for _, sub := range n.nodes {
+			v, err := d.value(sub)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case "string":
+		return d.text(n), nil
+	case "number":
+		lit := d.sca.Get(n.Token)
+		if d.UseNumber {
+			return json.Number(lit), nil
+		}
+		f, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return nil, d.typeError(n, float64Type)
+		}
+		return f, nil
+	case "bool":
+		return d.sca.Get(n.Token) == "true", nil
+	}
+	return nil, nil
+}
+
+// text returns the unescaped content of a string or key node.
+func (d *JSONDecoder) text(n *jsonNode) string {
+	raw := d.sca.Get(n.Token)
+	raw = raw[1 : len(raw)-1]
+	if strings.IndexByte(raw, '\\') < 0 {
+		return raw
+	}
+	b := &strings.Builder{}
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch raw[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r := hexRune(raw[i+1 : i+5])
+			i += 4
+			if utf16.IsSurrogate(r) {
+				r2 := utf8.RuneError
+				if strings.HasPrefix(raw[i+1:], `\u`) && len(raw) >= i+7 {
+					r2 = hexRune(raw[i+3 : i+7])
+				}
+				if dec := utf16.DecodeRune(r, r2); dec != unicode.ReplacementChar {
+					r = dec
+					i += 6
+				} else {
+					r = unicode.ReplacementChar
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(raw[i])
+		}
+	}
+	return b.String()
+}
+
+func hexRune(hex string) rune {
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	return rune(v)
+}
+
+// ------------------------------------------------------------------------------
+
+var (
+	float64Type         = reflect.TypeOf(0.0)
+	numberType          = reflect.TypeOf(json.Number(""))
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func (d *JSONDecoder) typeError(n *jsonNode, t reflect.Type) error {
+	return &JSONTypeError{
+		Position: d.sca.Position(n.From(), Runes),
+		Value:    n.Info,
+		Type:     t,
+	}
+}
+
+// decode stores the value of n in rv.
+func (d *JSONDecoder) decode(n *jsonNode, rv reflect.Value) error {
+	if n.Info == "null" {
+		switch rv.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+	}
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if rv.CanAddr() && rv.Addr().Type().Implements(unmarshalerType) {
+		u := rv.Addr().Interface().(json.Unmarshaler)
+		return u.UnmarshalJSON([]byte(d.sca.Get(n.Token)))
+	}
+	if rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		if n.Info != "string" {
+			return d.typeError(n, rv.Type())
+		}
+		u := rv.Addr().Interface().(encoding.TextUnmarshaler)
+		return u.UnmarshalText([]byte(d.text(n)))
+	}
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		v, err := d.value(n)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			rv.Set(reflect.ValueOf(v))
+		} else {
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+	switch n.Info {
+	case "object":
+		return d.decodeObject(n, rv)
+	case "array":
+		return d.decodeArray(n, rv)
+	case "string":
+		if rv.Kind() != reflect.String || rv.Type() == numberType {
+			return d.typeError(n, rv.Type())
+		}
+		rv.SetString(d.text(n))
+	case "number":
+		return d.decodeNumber(n, rv)
+	case "bool":
+		if rv.Kind() != reflect.Bool {
+			return d.typeError(n, rv.Type())
+		}
+		rv.SetBool(d.sca.Get(n.Token) == "true")
+	}
+	return nil
+}
+
+func (d *JSONDecoder) decodeNumber(n *jsonNode, rv reflect.Value) error {
+	lit := d.sca.Get(n.Token)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(lit, 10, 64)
+		if err != nil || rv.OverflowInt(i) {
+			return d.typeError(n, rv.Type())
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(lit, 10, 64)
+		if err != nil || rv.OverflowUint(u) {
+			return d.typeError(n, rv.Type())
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(lit, rv.Type().Bits())
+		if err != nil || rv.OverflowFloat(f) {
+			return d.typeError(n, rv.Type())
+		}
+		rv.SetFloat(f)
+	case reflect.String:
+		if rv.Type() != numberType {
+			return d.typeError(n, rv.Type())
+		}
+		rv.SetString(lit)
+	default:
+		return d.typeError(n, rv.Type())
+	}
+	return nil
+}
+
+func (d *JSONDecoder) decodeArray(n *jsonNode, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Slice:
+		rv.Set(reflect.MakeSlice(rv.Type(), len(n.nodes), len(n.nodes)))
+	case reflect.Array:
+		rv.Set(reflect.Zero(rv.Type()))
+	default:
+		return d.typeError(n, rv.Type())
+	}
+	for i, sub := range n.nodes {
+		if i >= rv.Len() {
+			break
+		}
+		if err := d.decode(sub, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *JSONDecoder) decodeObject(n *jsonNode, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Map:
+		t := rv.Type()
+		if !validKeyType(t.Key()) {
+			return d.typeError(n, t)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(t))
+		}
+		for _, m := range n.nodes {
+			key, err := d.decodeKey(m.nodes[0], t.Key())
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := d.decode(m.nodes[1], elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		fields := structFields(rv.Type())
+		for _, m := range n.nodes {
+			f, ok := fields.find(d.text(m.nodes[0]))
+			if !ok {
+				continue
+			}
+			decode := d.decode
+			if f.quoted {
+				decode = d.decodeQuoted
+			}
+			if err := decode(m.nodes[1], rv.FieldByIndex(f.index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return d.typeError(n, rv.Type())
+	}
+	return nil
+}
+
+// validKeyType checks if the keys of a JSON object can be stored as map keys of type t.
+func validKeyType(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// decodeKey returns the key of n as value of type t, like json.Unmarshal does for map keys.
+func (d *JSONDecoder) decodeKey(n *jsonNode, t reflect.Type) (reflect.Value, error) {
+	key := d.text(n)
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		kv := reflect.New(t)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return kv, &JSONTypeError{
+				Position: d.sca.Position(n.From(), Runes),
+				Value:    n.Info,
+				Type:     t,
+				Err:      err,
+			}
+		}
+		return kv.Elem(), nil
+	}
+	kv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		kv.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || kv.OverflowInt(i) {
+			return kv, d.typeError(n, t)
+		}
+		kv.SetInt(i)
+	default:
+		u, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || kv.OverflowUint(u) {
+			return kv, d.typeError(n, t)
+		}
+		kv.SetUint(u)
+	}
+	return kv, nil
+}
+
+// decodeQuoted stores the JSON value in the string of n in rv, for fields with the string option.
+func (d *JSONDecoder) decodeQuoted(n *jsonNode, rv reflect.Value) error {
+	if n.Info == "null" {
+		return d.decode(n, rv)
+	}
+	if n.Info != "string" {
+		return d.typeError(n, rv.Type())
+	}
+	if d.quoted == nil {
+		d.quoted = NewJSONDecoder()
+	}
+	if err := d.quoted.Decode(d.text(n), rv.Addr().Interface()); err != nil {
+		return d.typeError(n, rv.Type())
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+type jsonField struct {
+	name   string
+	index  []int
+	quoted bool
+}
+
+type jsonFields []jsonField
+
+// find returns the field with the name key, or a field that matches key case insensitive.
+func (fields jsonFields) find(key string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+var fieldCache sync.Map
+
+// structFields returns the exported fields of t with the names of the json tags.
+// The fields of embedded structs without a name count as fields of t.
+func structFields(t reflect.Type) jsonFields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(jsonFields)
+	}
+	fields := appendFields(jsonFields{}, t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func appendFields(fields jsonFields, t reflect.Type, index []int) jsonFields {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		idx := append(append([]int{}, index...), i)
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			fields = appendFields(fields, sf.Type, idx)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{name, idx, hasOption(opts[1:], "string") && quotable(sf.Type)})
+	}
+	return fields
+}
+
+func hasOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// quotable checks if the string option can be used for a field of type t.
+func quotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+package grammar
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	cases := []struct {
+		text string
+		exp  interface{}
+	}{
+		{`null`, nil},
+		{` true `, true},
+		{`-12.5e1`, -125.0},
+		{`"a\"b\\c\/\nä😀"`, "a\"b\\c/\nä😀"},
+		{`"\ud83d"`, "�"},
+		{`[]`, []interface{}{}},
+		{`[1, "x", [false]]`, []interface{}{1.0, "x", []interface{}{false}}},
+		{`{ "a" : {"b":null}, "c": [ ] }`, map[string]interface{}{
+			"a": map[string]interface{}{"b": nil},
+			"c": []interface{}{},
+		}},
+	}
+	for i, c := range cases {
+		v, err := DecodeJSON(c.text)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, c.exp) {
+			t.Errorf("%d unexpected value: %#v != %#v", i, v, c.exp)
+		}
+	}
+	for i, c := range jsonCases {
+		v, err := DecodeJSON(c)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		var exp interface{}
+		if err := json.Unmarshal([]byte(c), &exp); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, exp) {
+			t.Errorf("%d unexpected value: %v != %v", i, v, exp)
+		}
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	cases := []struct {
+		text string
+		exp  string
+	}{
+		{`{"a":}`, "json parse error: not able to read '}' at 1:6"},
+		{"[1,\n 2,\n x]", "json parse error: not able to read 'x' at 3:2"},
+		{`1 2`, "json parse error: not able to read end of text at 1:3"},
+		{`["a", "b`, "json parse error: not able to read end of text at 1:9"},
+		{`[1, 1e400]`, "json: can't decode number at 1:5 into Go value of type float64"},
+	}
+	for i, c := range cases {
+		_, err := DecodeJSON(c.text)
+		if err == nil {
+			t.Errorf("%d expected an error", i)
+			continue
+		}
+		if err.Error() != c.exp {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestJSONDecoderOptions(t *testing.T) {
+	d := NewJSONDecoder()
+	d.KeepOrder = true
+	d.UseNumber = true
+	v, err := d.Value(`{"z": 1, "a": 12345678901234567890, "m": {"y": 1.50}, "z": 2}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := JSONObject{
+		{"z", json.Number("1")},
+		{"a", json.Number("12345678901234567890")},
+		{"m", JSONObject{{"y", json.Number("1.50")}}},
+		{"z", json.Number("2")},
+	}
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("unexpected value: %#v", v)
+	}
+	if z, ok := v.(JSONObject).Get("z"); !ok || z != json.Number("2") {
+		t.Errorf("unexpected value for z: %v", z)
+	}
+}
+
+type decodeBase struct {
+	ID string
+}
+
+type decodeEntry struct {
+	decodeBase
+	Name    string            `json:"name"`
+	Tags    []string          `json:"tags,omitempty"`
+	Count   uint8             `json:"count"`
+	Ratio   *float64          `json:"ratio"`
+	Attrs   map[string]int    `json:"attrs"`
+	Pair    [2]bool           `json:"pair"`
+	Any     interface{}       `json:"any"`
+	Num     json.Number       `json:"num"`
+	When    time.Time         `json:"when"`
+	Skip    string            `json:"-"`
+	Raw     json.RawMessage   `json:"raw"`
+	Nested  *decodeEntry      `json:"nested"`
+	Labels  map[string]string `json:"labels"`
+	private int
+}
+
+func TestDecodeJSONInto(t *testing.T) {
+	text := `{
+		"ID": "x1",
+		"NAME": "first",
+		"tags": ["a", "b"],
+		"count": 200,
+		"ratio": 0.5,
+		"attrs": {"k": 3},
+		"pair": [true, false, true],
+		"any": [1, {"b": null}],
+		"num": 1e3,
+		"when": "2024-01-02T03:04:05Z",
+		"Skip": "no",
+		"raw": {"keep": [1, 2]},
+		"nested": {"name": "second", "nested": null},
+		"labels": null,
+		"unknown": {"x": [1, 2, 3]}
+	}`
+	e := decodeEntry{Skip: "yes", Labels: map[string]string{"x": "y"}}
+	if err := DecodeJSONInto(text, &e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ratio := 0.5
+	exp := decodeEntry{
+		decodeBase: decodeBase{"x1"},
+		Name:       "first",
+		Tags:       []string{"a", "b"},
+		Count:      200,
+		Ratio:      &ratio,
+		Attrs:      map[string]int{"k": 3},
+		Pair:       [2]bool{true, false},
+		Any:        []interface{}{1.0, map[string]interface{}{"b": nil}},
+		Num:        "1e3",
+		When:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Skip:       "yes",
+		Raw:        json.RawMessage(`{"keep": [1, 2]}`),
+		Nested:     &decodeEntry{Name: "second"},
+	}
+	if !reflect.DeepEqual(e, exp) {
+		t.Errorf("unexpected value:\n%#v\nexpected:\n%#v", e, exp)
+	}
+}
+
+func TestDecodeJSONIntoErrors(t *testing.T) {
+	var e decodeEntry
+	cases := []struct {
+		text string
+		v    interface{}
+		exp  string
+	}{
+		{`{}`, e, "json: Unmarshal(non-pointer grammar.decodeEntry)"},
+		{`{"count": 256}`, &e, "json: can't decode number at 1:11 into Go value of type uint8"},
+		{"{\n \"tags\": [\"a\", 1]}", &e, "json: can't decode number at 2:16 into Go value of type string"},
+		{`{"attrs": []}`, &e, "json: can't decode array at 1:11 into Go value of type map[string]int"},
+		{`{"name": true}`, &e, "json: can't decode bool at 1:10 into Go value of type string"},
+		{`"x"`, &e.Num, "json: can't decode string at 1:1 into Go value of type json.Number"},
+		{`{"a":1}`, &map[int]int{}, "json: can't decode key at 1:2 into Go value of type int"},
+		{`{"a":1}`, &map[bool]int{}, "json: can't decode object at 1:1 into Go value of type map[bool]int"},
+		{`{"n": 1}`, &decodeTypes{}, "json: can't decode number at 1:7 into Go value of type int"},
+		{`{"n": "x"}`, &decodeTypes{}, "json: can't decode string at 1:7 into Go value of type int"},
+		{"{\"a\": 1,\n \"zz\": 2}", &map[hexKey]int{}, `json: can't decode key at 2:2 into Go value of type grammar.hexKey: strconv.ParseInt: parsing "zz": invalid syntax`},
+	}
+	for i, c := range cases {
+		err := DecodeJSONInto(c.text, c.v)
+		if err == nil {
+			t.Errorf("%d expected an error", i)
+			continue
+		}
+		if err.Error() != c.exp {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+	}
+
+	var numErr *strconv.NumError
+	if err := DecodeJSONInto(`{"zz": 1}`, &map[hexKey]int{}); !errors.As(err, &numErr) {
+		t.Errorf("the key error should wrap the error of UnmarshalText: %v", err)
+	}
+}
+
+type hexKey int
+
+func (k *hexKey) UnmarshalText(text []byte) error {
+	v, err := strconv.ParseInt(string(text), 16, 64)
+	*k = hexKey(v)
+	return err
+}
+
+type upperText string
+
+func (u *upperText) UnmarshalText(text []byte) error {
+	*u = upperText(strings.ToUpper(string(text)))
+	return nil
+}
+
+type decodeTypes struct {
+	N     int                  `json:"n,string"`
+	Flag  *bool                `json:"flag,string"`
+	Name  string               `json:"name,string"`
+	Tags  []int                `json:"tags,string"`
+	Upper upperText            `json:"upper"`
+	ByID  map[int]string       `json:"byID"`
+	ByU   map[uint8]bool       `json:"byU"`
+	ByKey map[upperText]string `json:"byKey"`
+}
+
+func TestDecodeJSONIntoTypes(t *testing.T) {
+	text := `{
+		"n": "-12",
+		"flag": "true",
+		"name": "\"x\"",
+		"tags": [1, 2],
+		"upper": "abc",
+		"byID": {"1": "a", "-2": "b"},
+		"byU": {"255": true},
+		"byKey": {"k": "v"}
+	}`
+	var v, exp decodeTypes
+	if err := DecodeJSONInto(text, &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(text), &exp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("unexpected value:\n%#v\nexpected:\n%#v", v, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func BenchmarkDecodeJSON(b *testing.B) {
+	text := jsonCases[2]
+	d := NewJSONDecoder()
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		if _, err := d.Value(text); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodingJSON(b *testing.B) {
+	data := []byte(jsonCases[2])
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type benchMenu struct {
+	Menu struct {
+		ID    string `json:"id"`
+		Value string `json:"value"`
+		Popup struct {
+			MenuItem []struct {
+				Value   string `json:"value"`
+				OnClick string `json:"onclick"`
+			} `json:"menuitem"`
+		} `json:"popup"`
+	} `json:"menu"`
+}
+
+func BenchmarkDecodeJSONInto(b *testing.B) {
+	text := jsonCases[3]
+	d := NewJSONDecoder()
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		var m benchMenu
+		if err := d.Decode(text, &m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodingJSONInto(b *testing.B) {
+	data := []byte(jsonCases[3])
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var m benchMenu
+		if err := json.Unmarshal(data, &m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
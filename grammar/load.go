@@ -0,0 +1,514 @@
+package grammar
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	. "github.com/aiq/tok"
+)
+
+// Definition is a Grammar that was loaded from a grammar file.
+//
+// A grammar file defines one rule per line with name = expression, lines that
+// start with a whitespace continue the rule of the previous line and # starts a comment.
+// The first rule is the start rule of the Grammar.
+// An expression is built with the following elements:
+//
+//	a b          sequence
+//	a | b        alternatives
+//	( a )        group
+//	"lit"        literal, also as `raw` string
+//	~"lit"       case insensitive literal
+//	'a'          rune
+//	'a'..'z'     rune range
+//	'a'..'z'-["xy"]  rune range without the runes of the string
+//	["abc"]      any rune of the string
+//	*a +a ?a     zero or more, one or more, optional
+//	!a           any rune that does not match a
+//	@a           at a, doesn't move the Scanner
+//	->a -->a     to and past
+//	-(a)->b -(a)-->b  to and past b, all runes before b match a
+//	$j<a $j      janus pair, $j expects the runes that $j<a has read
+//	3*a {2,4}*a  times and repeat, {1,}*a has no upper limit
+//	name         rule or one of ANY, DIGIT, END, HEX, INT, NL, UINT, WS
+//
+// The prefix notation matches the notation of What, the Lines of a Definition can be loaded again.
+type Definition struct {
+	Name   string
+	Rules  []*Rule
+	index  map[string]*Rule
+	janus  map[string]Reader
+	bodies []string
+}
+
+var builtins = map[string]func() Reader{
+	"ANY":   func() Reader { return Between(0, unicode.MaxRune) },
+	"DIGIT": Digit,
+	"END":   AtEnd,
+	"HEX":   HexDigit,
+	"INT":   func() Reader { return Int(10, 64) },
+	"NL":    NL,
+	"UINT":  func() Reader { return Uint(10, 64) },
+	"WS":    WS,
+}
+
+// Load creates a Definition from the text of a grammar file.
+func Load(name string, text string) (*Definition, error) {
+	d := &Definition{
+		Name:  name,
+		index: map[string]*Rule{},
+		janus: map[string]Reader{},
+	}
+	sca := NewScanner(text)
+	bodies := []Token{}
+	for !sca.AtEnd() {
+		if !startsRule(sca.Tail()) {
+			toLineEnd(sca)
+			sca.IfRune('\n')
+			continue
+		}
+		ruleName, err := sca.CaptureUse(RuleName())
+		if err != nil {
+			return nil, fmt.Errorf("invalid grammar %s: %w", name, err)
+		}
+		if _, ok := d.index[ruleName]; ok {
+			return nil, fmt.Errorf("invalid grammar %s: rule %s is defined twice", name, ruleName)
+		}
+		sca.WhileAnyRune(" \t")
+		if err := sca.ErrorIfFalse(sca.IfRune('='), "'='"); err != nil {
+			return nil, fmt.Errorf("invalid grammar %s: %w", name, err)
+		}
+		from := sca.Mark()
+		for {
+			toLineEnd(sca)
+			if !sca.IfRune('\n') || startsRule(sca.Tail()) {
+				break
+			}
+		}
+		r := &Rule{Name: ruleName}
+		d.Rules = append(d.Rules, r)
+		d.index[ruleName] = r
+		bodies = append(bodies, MakeToken(from, sca.Mark()))
+	}
+	if len(d.Rules) == 0 {
+		return nil, fmt.Errorf("invalid grammar %s: no rules", name)
+	}
+
+	// a body that uses the end of a janus pair before the body with the begin is loaded again later
+	pending := []int{}
+	for i := range bodies {
+		pending = append(pending, i)
+	}
+	for len(pending) > 0 {
+		retry := []int{}
+		var first error
+		for _, i := range pending {
+			l := &loader{d: d, sca: sca.Window(bodies[i]), janus: map[string]Reader{}}
+			reader, err := l.expr()
+			if err == nil {
+				l.skip()
+				err = l.sca.ErrorIfFalse(l.sca.AtEnd(), "expression")
+			}
+			if err != nil && !l.missing {
+				return nil, fmt.Errorf("invalid grammar %s: %w", name, err)
+			}
+			if err != nil {
+				retry = append(retry, i)
+				if first == nil {
+					first = err
+				}
+				continue
+			}
+			for j, end := range l.janus {
+				d.janus[j] = end
+			}
+			d.Rules[i].Reader = reader
+		}
+		if len(retry) == len(pending) {
+			return nil, fmt.Errorf("invalid grammar %s: %w", name, first)
+		}
+		pending = retry
+	}
+	for _, body := range bodies {
+		d.bodies = append(d.bodies, compactBody(sca.Get(body)))
+	}
+	return d, nil
+}
+
+// LoadFile creates a Definition from a grammar file, the file name will be used as the name.
+func LoadFile(filename string) (*Definition, error) {
+	text, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Load(filename, string(text))
+}
+
+// Open returns the Grammar json, jsonc, json5, lua or mxt, any other name will be loaded as grammar file.
+func Open(name string) (Grammar, error) {
+	switch name {
+	case "json":
+		return JSON(), nil
+	case "jsonc":
+		return JSONC(), nil
+	case "json5":
+		return JSON5(), nil
+	case "lua":
+		return Lua(), nil
+	case "mxt":
+		return MXT(), nil
+	}
+	d, err := LoadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// toLineEnd moves sca to the next line break or to the end.
+func toLineEnd(sca *Scanner) {
+	if !sca.ToRune('\n') {
+		sca.ToEnd()
+	}
+}
+
+// startsRule checks if a line that starts with str defines a rule.
+func startsRule(str string) bool {
+	if str == "" {
+		return false
+	}
+	switch str[0] {
+	case ' ', '\t', '\r', '\n', '#':
+		return false
+	}
+	return true
+}
+
+// Rule returns the Rule with name.
+func (d *Definition) Rule(name string) (*Rule, bool) {
+	r, ok := d.index[name]
+	return r, ok
+}
+
+// Lines returns one line per rule in the notation of a grammar file, without comments and line breaks.
+// Load creates the same Definition from the lines.
+func (d *Definition) Lines() []string {
+	res := []string{}
+	for i, r := range d.Rules {
+		res = append(res, r.Name+" = "+d.bodies[i])
+	}
+	return res
+}
+
+func (d *Definition) Grammar() []*Rule {
+	return d.Rules
+}
+
+func (d *Definition) Read(s *Scanner) error {
+	err := d.Rules[0].Read(s)
+	if err != nil {
+		return fmt.Errorf("%s parse error: %w", d.Name, err)
+	}
+	return nil
+}
+
+func (d *Definition) What() string {
+	return d.Name
+}
+
+// compactBody removes the comments of a rule body and replaces each run of whitespaces with one space.
+func compactBody(body string) string {
+	l := &loader{sca: NewScanner(body)}
+	b := strings.Builder{}
+	for {
+		m := l.sca.Mark()
+		l.skip()
+		if l.sca.AtEnd() {
+			return b.String()
+		}
+		if l.sca.Mark() > m && b.Len() > 0 {
+			b.WriteRune(' ')
+		}
+		m = l.sca.Mark()
+		if _, err := l.quoted('"', '`', '\''); err != nil {
+			l.sca.MoveRunes(1)
+		}
+		b.WriteString(l.sca.Since(m))
+	}
+}
+
+// ------------------------------------------------------------------------------
+type loader struct {
+	d       *Definition
+	sca     *Scanner
+	janus   map[string]Reader
+	missing bool
+}
+
+// skip skips whitespaces and comments.
+func (l *loader) skip() {
+	for {
+		if l.sca.IfRune('#') {
+			toLineEnd(l.sca)
+		} else if !l.sca.IfAnyRune(" \t\r\n") {
+			return
+		}
+	}
+}
+
+func (l *loader) expr() (Reader, error) {
+	list := []interface{}{}
+	for {
+		r, err := l.seq()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+		l.skip()
+		if !l.sca.IfRune('|') {
+			break
+		}
+	}
+	if len(list) == 1 {
+		return list[0].(Reader), nil
+	}
+	return Any(list...), nil
+}
+
+func (l *loader) seq() (Reader, error) {
+	list := []interface{}{}
+	for {
+		l.skip()
+		if l.sca.AtEnd() || l.sca.Tail()[0] == '|' || l.sca.Tail()[0] == ')' {
+			break
+		}
+		r, err := l.prefix()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	switch len(list) {
+	case 0:
+		return nil, l.sca.ErrorFor("expression")
+	case 1:
+		return list[0].(Reader), nil
+	}
+	return Seq(list...), nil
+}
+
+func (l *loader) prefix() (Reader, error) {
+	sca := l.sca
+	wrap := func(f func(i interface{}) Reader) (Reader, error) {
+		r, err := l.prefix()
+		if err != nil {
+			return nil, err
+		}
+		return f(r), nil
+	}
+	switch {
+	case sca.IfRune('*'):
+		return wrap(Zom)
+	case sca.IfRune('+'):
+		return wrap(Many)
+	case sca.IfRune('?'):
+		return wrap(Opt)
+	case sca.IfRune('!'):
+		return wrap(func(i interface{}) Reader { return Not(i.(Reader)) })
+	case sca.IfRune('@'):
+		return wrap(func(i interface{}) Reader { return At(i.(Reader)) })
+	case sca.If("-("):
+		body, err := l.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := sca.ErrorIfFalse(sca.IfRune(')'), "')'"); err != nil {
+			return nil, err
+		}
+		switch {
+		case sca.If("-->"):
+			return wrap(func(i interface{}) Reader { return BodyTail(body, i.(Reader)) })
+		case sca.If("->"):
+			return wrap(func(i interface{}) Reader { return Body(body, i.(Reader)) })
+		}
+		return nil, sca.ErrorFor("'->' or '-->'")
+	case sca.IfRune('$'):
+		return l.janusReader()
+	case sca.If("-->"):
+		return wrap(Past)
+	case sca.If("->"):
+		return wrap(To)
+	case sca.IfRune('~'):
+		str, err := l.quoted('"', '`')
+		if err != nil {
+			return nil, err
+		}
+		return Fold(str), nil
+	case sca.IfRune('{'):
+		min, err := sca.ReadInt(10, 32)
+		if err != nil {
+			return nil, err
+		}
+		max := int64(-1)
+		if err := sca.ErrorIfFalse(sca.IfRune(','), "','"); err != nil {
+			return nil, err
+		}
+		if !sca.IfRune('}') {
+			if max, err = sca.ReadInt(10, 32); err != nil {
+				return nil, err
+			}
+			if err := sca.ErrorIfFalse(sca.IfRune('}'), "'}'"); err != nil {
+				return nil, err
+			}
+		}
+		if err := sca.ErrorIfFalse(sca.IfRune('*'), "'*'"); err != nil {
+			return nil, err
+		}
+		return wrap(func(i interface{}) Reader { return Repeat(int(min), int(max), i.(Reader)) })
+	case sca.IfBetween('0', '9'):
+		sca.Move(-1)
+		n, err := sca.ReadInt(10, 32)
+		if err != nil {
+			return nil, err
+		}
+		if err := sca.ErrorIfFalse(sca.IfRune('*'), "'*'"); err != nil {
+			return nil, err
+		}
+		return wrap(func(i interface{}) Reader { return Times(int(n), i.(Reader)) })
+	}
+	return l.primary()
+}
+
+func (l *loader) primary() (Reader, error) {
+	sca := l.sca
+	if sca.AtEnd() {
+		return nil, sca.ErrorFor("expression")
+	}
+	switch sca.Tail()[0] {
+	case '"', '`':
+		str, err := l.quoted('"', '`')
+		if err != nil {
+			return nil, err
+		}
+		return Lit(str), nil
+	case '\'':
+		min, err := l.quotedRune()
+		if err != nil {
+			return nil, err
+		}
+		if !sca.If("..") {
+			return Rune(min), nil
+		}
+		max, err := l.quotedRune()
+		if err != nil {
+			return nil, err
+		}
+		if !sca.If("-[") {
+			return Between(min, max), nil
+		}
+		holes, err := l.quoted('"', '`')
+		if err != nil {
+			return nil, err
+		}
+		return Holey(min, max, holes), sca.ErrorIfFalse(sca.IfRune(']'), "']'")
+	case '[':
+		sca.Move(1)
+		str, err := l.quoted('"', '`')
+		if err != nil {
+			return nil, err
+		}
+		return AnyRune(str), sca.ErrorIfFalse(sca.IfRune(']'), "']'")
+	case '(':
+		sca.Move(1)
+		r, err := l.expr()
+		if err != nil {
+			return nil, err
+		}
+		return r, sca.ErrorIfFalse(sca.IfRune(')'), "')'")
+	}
+	m := sca.Mark()
+	name, err := sca.CaptureUse(RuleName())
+	if err != nil {
+		return nil, sca.ErrorFor("expression")
+	}
+	if r, ok := l.d.index[name]; ok {
+		return r, nil
+	}
+	if f, ok := builtins[name]; ok {
+		return f(), nil
+	}
+	sca.ToMarker(m)
+	return nil, sca.ErrorFor("known rule")
+}
+
+// janusReader reads the part of $j<a or $j after the $.
+func (l *loader) janusReader() (Reader, error) {
+	sca := l.sca
+	name, _ := sca.CaptureUse(RuleName())
+	if sca.IfRune('<') {
+		sub, err := l.prefix()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := l.d.janus[name]; ok {
+			return nil, fmt.Errorf("janus $%s is defined twice", name)
+		}
+		if _, ok := l.janus[name]; ok {
+			return nil, fmt.Errorf("janus $%s is defined twice", name)
+		}
+		beg, end := Janus(name, sub)
+		l.janus[name] = end
+		return beg, nil
+	}
+	if end, ok := l.janus[name]; ok {
+		return end, nil
+	}
+	if end, ok := l.d.janus[name]; ok {
+		return end, nil
+	}
+	l.missing = true
+	return nil, sca.ErrorFor("known janus")
+}
+
+// quoted reads a Go string literal that uses one of the quote runes.
+func (l *loader) quoted(quotes ...rune) (string, error) {
+	sca := l.sca
+	m := sca.Mark()
+	for _, q := range quotes {
+		if !sca.IfRune(q) {
+			continue
+		}
+		for !sca.IfRune(q) {
+			if sca.AtEnd() || (q != '`' && sca.IfRune('\n')) {
+				sca.ToMarker(m)
+				return "", sca.ErrorFor("string")
+			}
+			if q != '`' && sca.IfRune('\\') {
+				sca.Move(1)
+			} else {
+				sca.MoveRunes(1)
+			}
+		}
+		str, err := strconv.Unquote(sca.Since(m))
+		if err != nil {
+			sca.ToMarker(m)
+			return "", sca.ErrorFor("string")
+		}
+		return str, nil
+	}
+	return "", sca.ErrorFor("string")
+}
+
+func (l *loader) quotedRune() (rune, error) {
+	m := l.sca.Mark()
+	str, err := l.quoted('\'')
+	runes := []rune(str)
+	if err != nil || len(runes) != 1 {
+		l.sca.ToMarker(m)
+		return 0, l.sca.ErrorFor("rune")
+	}
+	return runes[0], nil
+}
@@ -0,0 +1,147 @@
+package grammar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+)
+
+const listGrammar = `# a list of numbers and names
+list   = '[' ?(item *(',' item)) ']' END
+item   = ws (number | name | ~"nil") ws
+number = ?'-' +DIGIT
+name   = ('a'..'z' | ["_"])
+         *('a'..'z' | DIGIT)   # continues the rule
+ws     = *[" \t"]
+`
+
+func TestLoad(t *testing.T) {
+	g, err := Load("list", listGrammar)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(g.Grammar()) != 5 {
+		t.Errorf("expected 5 rules, got %d", len(g.Grammar()))
+	}
+	if err := tok.CheckRules(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	posCases := []string{
+		`[]`,
+		`[1, -23, abc, _x1]`,
+		`[ NIL ]`,
+	}
+	for i, c := range posCases {
+		if err := tok.NewScanner(c).Use(g); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+	}
+
+	negCases := []string{
+		`[`,
+		`[1,]`,
+		`[1] `,
+		`[A]`,
+	}
+	for i, c := range negCases {
+		if err := tok.NewScanner(c).Use(g); err == nil {
+			t.Errorf("%d expected an error", i)
+		}
+	}
+}
+
+const longGrammar = `text  = (str | long | line) END
+close = -->(']' $eq ']')
+long  = '[' $eq<*'=' '[' close
+str   = '"' *'a'..'z'-["q"] '"'
+line  = -(*('a'..'z' | ' '))-->'\n'
+`
+
+func TestLoadNotation(t *testing.T) {
+	g, err := Load("long", longGrammar)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	posCases := []string{
+		`"abc"`,
+		`[[a]]`,
+		`[==[a]]]=]b]==]`,
+		"a b\n",
+	}
+	for i, c := range posCases {
+		if err := tok.NewScanner(c).Use(g); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+	}
+	negCases := []string{
+		`"aqb"`,
+		`[=[a]==]`,
+		"a1\n",
+	}
+	for i, c := range negCases {
+		if err := tok.NewScanner(c).Use(g); err == nil {
+			t.Errorf("%d expected an error", i)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		text string
+		exp  string
+	}{
+		{"", "invalid grammar x: no rules"},
+		{"a 'x'", "invalid grammar x: not able to read '=' at 2"},
+		{"a = b", "invalid grammar x: not able to read known rule at 4"},
+		{"a = 'x'\na = 'y'", "invalid grammar x: rule a is defined twice"},
+		{"a = ('x'", "invalid grammar x: not able to read ')' at 8"},
+		{"a = \"x", "invalid grammar x: not able to read string at 4"},
+		{"a = 'xy'", "invalid grammar x: not able to read rune at 4"},
+		{"a = 'x' )", "invalid grammar x: not able to read expression at 8"},
+		{"a = 'a'..'z'-[\"x\"", "invalid grammar x: not able to read ']' at 17"},
+		{"a = -('x') 'y'", "invalid grammar x: not able to read '->' or '-->' at 10"},
+		{"a = $j 'x'\nb = 'y'", "invalid grammar x: not able to read known janus at 6"},
+		{"a = $j<'x' $j<'y'", "invalid grammar x: janus $j is defined twice"},
+	}
+	for i, c := range cases {
+		_, err := Load("x", c.text)
+		if err == nil || err.Error() != c.exp {
+			t.Errorf("%d expected error %q, got %v", i, c.exp, err)
+		}
+	}
+}
+
+func TestLoadLines(t *testing.T) {
+	g, err := Load("list", listGrammar+"comment = \"/*\" -->\"*/\" | \"--\" ->(NL | END) # to or past\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := g.Lines()
+	exp := []string{
+		`list = '[' ?(item *(',' item)) ']' END`,
+		`item = ws (number | name | ~"nil") ws`,
+		`number = ?'-' +DIGIT`,
+		`name = ('a'..'z' | ["_"]) *('a'..'z' | DIGIT)`,
+		`ws = *[" \t"]`,
+		`comment = "/*" -->"*/" | "--" ->(NL | END)`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("unexpected lines:\n%s", strings.Join(lines, "\n"))
+	}
+	if r, _ := g.Rule("comment"); r.Rule() != `comment: [ "/*" -->"*/" "--" ->[ [ "\n" "\r\n" ] @END ] ]` {
+		t.Errorf("unexpected rule: %s", r.Rule())
+	}
+
+	again, err := Load("list", strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(again.Lines(), "\n") != strings.Join(lines, "\n") {
+		t.Errorf("unexpected lines after the round trip:\n%s", strings.Join(again.Lines(), "\n"))
+	}
+	if strings.Join(tok.GrammarLines(again.Grammar()), "\n") != strings.Join(tok.GrammarLines(g.Grammar()), "\n") {
+		t.Errorf("the round trip changed the rules")
+	}
+}
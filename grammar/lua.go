@@ -220,7 +220,7 @@ func Lua() *LuaReader {
 func (r *LuaReader) Read(s *Scanner) error {
 	err := r.Chunk.Read(s)
 	if err != nil {
-		return fmt.Errorf("lua parse error: %v", err)
+		return fmt.Errorf("lua parse error: %w", err)
 	}
 	return nil
 }
@@ -229,6 +229,13 @@ func (r *LuaReader) What() string {
 	return "lua"
 }
 
+// Grammar returns the Rules with the start rule chunk as first Rule.
 func (r *LuaReader) Grammar() []*Rule {
-	return CollectRules(r)
+	rules := []*Rule{&r.Chunk}
+	for _, rule := range CollectRules(r) {
+		if rule != &r.Chunk {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
 }
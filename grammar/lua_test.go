@@ -1,9 +1,11 @@
 package grammar
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/aiq/tok"
+	"github.com/aiq/tok/stream"
 )
 
 func TestLuaParts(t *testing.T) {
@@ -34,15 +36,17 @@ func TestLuaParts(t *testing.T) {
 	}
 }
 
+var luaCases = []string{
+	``,
+	`local x = 1`,
+	`assert( dir and dir ~= "", "directory parameter is missing or empty" )`,
+	`if not isdodd( base ) then base = doSomething( base ) end`,
+	`return coroutine.wrap( function() yieldtree( dir ) end )`,
+}
+
 func TestLua(t *testing.T) {
-	posCases := []struct {
-		lua       string
-		funcnames []string
-	}{
-		{``, []string{}},
-	}
-	for i, c := range posCases {
-		sca := tok.NewScanner(c.lua)
+	for i, c := range luaCases {
+		sca := tok.NewScanner(c)
 		r := Lua()
 		err := sca.Use(r)
 		if err != nil {
@@ -53,3 +57,132 @@ func TestLua(t *testing.T) {
 		}
 	}
 }
+
+var luaScript = `-- walks a directory tree
+local lfs = require "lfs"
+
+local function yieldtree( dir )
+	for entry in lfs.dir( dir ) do
+		if entry ~= "." and entry ~= ".." then
+			local path = dir.."/"..entry
+			local attr = lfs.attributes( path ) --[[ may be nil ]]
+			coroutine.yield( path, attr )
+			if attr.mode == "directory" then
+				yieldtree( path )
+			end
+		end
+	end
+end
+
+function M.tree( dir )
+	assert( dir and dir ~= "", "directory parameter is missing or empty" )
+	local sizes = { files = 0, [ "dirs" ] = 0; total = 0x10 }
+	for i = 1, #dir, 2 do sizes.total = sizes.total + i * 2.5e-1 end
+	return coroutine.wrap( function() yieldtree( dir ) end )
+end
+`
+
+func TestLuaIncremental(t *testing.T) {
+	inp := luaScript
+	at := func(sub string) tok.Marker {
+		return tok.Marker(strings.Index(inp, sub))
+	}
+	edits := []func() tok.Edit{
+		func() tok.Edit { return tok.MakeEdit(at("0x10"), at("0x10")+4, "16") },
+		func() tok.Edit { return tok.MakeEdit(at("may be nil"), at("may be nil")+3, "can") },
+		func() tok.Edit { return tok.MakeEdit(at("2.5e-1"), at("2.5e-1")+6, "0.25") },
+		func() tok.Edit { return tok.MakeEdit(at(`"lfs"`)+1, at(`"lfs"`)+4, "lfs2") },
+	}
+	inc := tok.NewIncremental("lua", Lua())
+	if _, err := inc.Parse(inp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, f := range edits {
+		e := f()
+		inp, _ = e.Apply(inp)
+		g, changed, err := inc.Edit(e)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		exp, err := tok.NewIncremental("lua", Lua()).Parse(inp)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if !g.Equal(exp) {
+			t.Errorf("%d unexpected graph:\n%s!=\n%s", i, g.FlameStack(), exp.FlameStack())
+		}
+		if len(changed) != 1 || changed[0].Len() >= len(inp) {
+			t.Errorf("%d expected a partial reparse, changed: %v", i, changed)
+		}
+	}
+}
+
+func TestLuaStream(t *testing.T) {
+	g := LuaStream()
+	for i, c := range append(luaCases, luaScript) {
+		s, err := stream.Lex(LuaLexer(), tok.NewScanner(c))
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if err := s.Use(g); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+		if !s.AtEnd() {
+			t.Errorf("%d did not read the whole lua", i)
+		}
+	}
+	s, err := stream.Lex(LuaLexer(), tok.NewScanner("local x = = 1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Use(stream.Seq(g, stream.AtEnd())); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestLuaLexer(t *testing.T) {
+	sca := tok.NewScanner(`local endx = a.."b" -- c` + "\n" + `return 0x1F // #t`)
+	segs, err := LuaLexer().Tokenize(sca)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	infos := []string{}
+	for _, seg := range segs {
+		infos = append(infos, seg.Info+":"+sca.Get(seg.Token))
+	}
+	exp := `keyword:local name:endx symbol:= name:a symbol:.. string:"b" keyword:return number:0x1F symbol:// symbol:# name:t`
+	if strings.Join(infos, " ") != exp {
+		t.Errorf("unexpected tokens: %s", strings.Join(infos, " "))
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func BenchmarkLua(b *testing.B) {
+	text := strings.Repeat(luaScript, 10)
+	g := Lua()
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		sca := tok.NewScanner(text)
+		if err := sca.Use(g); err != nil || !sca.AtEnd() {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLuaStream(b *testing.B) {
+	text := strings.Repeat(luaScript, 10)
+	l := LuaLexer()
+	g := LuaStream()
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		s, err := stream.Lex(l, tok.NewScanner(text))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := s.Use(g); err != nil || !s.AtEnd() {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package grammar
+
+import (
+	"fmt"
+
+	. "github.com/aiq/tok"
+	"github.com/aiq/tok/stream"
+)
+
+var luaKeywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "goto": true, "if": true, "in": true,
+	"local": true, "nil": true, "not": true, "or": true, "repeat": true, "return": true,
+	"then": true, "true": true, "until": true, "while": true,
+}
+
+var luaSymbols = []interface{}{
+	"...", "..", "::", "<<", ">>", "//", "==", "~=", "<=", ">=",
+	"+", "-", "*", "/", "%", "^", "#", "&", "~", "|", "<", ">", "=",
+	"(", ")", "{", "}", "[", "]", ";", ":", ",", ".",
+}
+
+// LuaLexer creates a Lexer for the tokens of a Lua file.
+// Whitespaces and comments are skipped, the tokens have the Info keyword, name, number, string or symbol.
+func LuaLexer() *Lexer {
+	name := Seq(Set("a-zA-Z", "_"), Zom(Set("a-zA-Z0-9", "_")))
+	keyword := Wrap("keyword", func(s *Scanner) error {
+		str, err := s.CaptureUse(name)
+		if err == nil && !luaKeywords[str] {
+			return s.ErrorFor("keyword")
+		}
+		return err
+	})
+	return NewLexer(
+		LexSkip("ws", Many(WS())),
+		LexSkip("comment", LuaComment()),
+		Lex("keyword", keyword),
+		Lex("name", name),
+		Lex("number", Seq(At(Digit()), LuaNumeral())),
+		Lex("string", Seq(At(AnyRune(`"'[`)), LuaString())),
+		Lex("symbol", Seq(At(Not(Any(Set("a-zA-Z0-9", "_"), WS()))), Any(luaSymbols...))),
+	)
+}
+
+// LuaStreamReader reads the tokens of LuaLexer, the Rules match the Rules of LuaReader.
+type LuaStreamReader struct {
+	Name             stream.Rule
+	Field            stream.Rule
+	FieldList        stream.Rule
+	TableConstructor stream.Rule
+	FuncParams       stream.Rule
+	FuncBody         stream.Rule
+	FuncArgs         stream.Rule
+	FuncCall         stream.Rule
+	PrefixExp        stream.Rule
+	FinalExp         stream.Rule
+	Exp              stream.Rule
+	ExpList          stream.Rule
+	NameList         stream.Rule
+	VarSuffix        stream.Rule
+	Var              stream.Rule
+	VarList          stream.Rule
+	FuncName         stream.Rule
+	RetStat          stream.Rule
+	AttNameList      stream.Rule
+	Stat             stream.Rule
+	Block            stream.Rule
+}
+
+// LuaStream creates a Grammar to Read the tokens of a Lua file that LuaLexer creates.
+// The Grammar avoids that each alternative of LuaReader reads the whitespaces and comments again.
+func LuaStream() *LuaStreamReader {
+	g := &LuaStreamReader{}
+	s := stream.Seq
+	name := stream.Kind("name")
+	g.Name = stream.Rule{Name: "Name", Reader: name}
+	g.NameList = stream.Rule{Name: "namelist", Reader: s(&g.Name, stream.Zom(s(",", &g.Name)))}
+
+	unOp := stream.Any("-", "not", "#", "~")
+	binOp := stream.Any(
+		"..", "<=", "<", ">=", ">", "==", "~=", "and", "or",
+		"+", "-", "*", "/", "//", "^", "%", "&", "~", "|", ">>", "<<",
+	)
+	fieldSep := stream.Any(",", ";")
+	g.Field = stream.Rule{Name: "field", Reader: stream.Any(
+		s("[", &g.Exp, "]", "=", &g.Exp),
+		s(&g.Name, "=", &g.Exp),
+		&g.Exp,
+	)}
+	g.FieldList = stream.Rule{Name: "fieldlist", Reader: s(&g.Field, stream.Zom(s(fieldSep, &g.Field)), stream.Opt(fieldSep))}
+	g.TableConstructor = stream.Rule{Name: "tableconstructor", Reader: s("{", stream.Opt(&g.FieldList), "}")}
+
+	nameAndArgs := s(stream.Opt(s(":", &g.Name)), &g.FuncArgs)
+	varOrExp := stream.Any(&g.Var, s("(", &g.Exp, ")"))
+
+	g.FuncParams = stream.Rule{Name: "funcparams", Reader: stream.Any(s(&g.NameList, stream.Opt(s(",", "..."))), "...")}
+	g.FuncBody = stream.Rule{Name: "funcbody", Reader: s("(", stream.Opt(&g.FuncParams), ")", &g.Block, "end")}
+	g.FuncArgs = stream.Rule{Name: "funcargs", Reader: stream.Any(
+		s("(", stream.Opt(&g.ExpList), ")"),
+		&g.TableConstructor,
+		stream.Kind("string"),
+	)}
+	g.FuncCall = stream.Rule{Name: "funccall", Reader: s(varOrExp, stream.Many(nameAndArgs))}
+
+	g.PrefixExp = stream.Rule{Name: "prefixexp", Reader: s(varOrExp, stream.Zom(nameAndArgs))}
+	g.FinalExp = stream.Rule{Name: "finalexp", Reader: stream.Any(
+		"nil", "false", "true",
+		stream.Kind("number"), stream.Kind("string"), "...",
+		s("function", &g.FuncBody),
+		s(unOp, &g.Exp),
+		&g.TableConstructor,
+		&g.PrefixExp,
+	)}
+	g.Exp = stream.Rule{Name: "exp", Reader: stream.Any(
+		s(&g.FinalExp, binOp, &g.Exp),
+		&g.FinalExp,
+	)}
+	g.ExpList = stream.Rule{Name: "explist", Reader: s(&g.Exp, stream.Zom(s(",", &g.Exp)))}
+	g.VarSuffix = stream.Rule{Name: "varsuffix", Reader: s(stream.Zom(nameAndArgs), stream.Any(
+		s("[", &g.Exp, "]"),
+		s(".", &g.Name),
+	))}
+	g.Var = stream.Rule{Name: "var", Reader: s(stream.Any(
+		&g.Name,
+		s("(", &g.Exp, ")", &g.VarSuffix),
+	), stream.Zom(&g.VarSuffix))}
+	g.VarList = stream.Rule{Name: "varlist", Reader: s(&g.Var, stream.Zom(s(",", &g.Var)))}
+
+	g.FuncName = stream.Rule{Name: "funcname", Reader: s(&g.Name, stream.Zom(s(".", &g.Name)), stream.Opt(s(":", &g.Name)))}
+	g.RetStat = stream.Rule{Name: "retstat", Reader: s("return", stream.Opt(&g.ExpList), stream.Opt(";"))}
+	attrib := stream.Opt(s("<", &g.Name, ">"))
+	g.AttNameList = stream.Rule{Name: "attnamelist", Reader: s(&g.Name, attrib, stream.Zom(s(",", &g.Name, attrib)))}
+	g.Stat = stream.Rule{Name: "stat", Reader: stream.Any(
+		";",
+		s(&g.VarList, "=", &g.ExpList),
+		s("::", &g.Name, "::"),
+		"break",
+		s("goto", &g.Name),
+		s("do", &g.Block, "end"),
+		s("while", &g.Exp, "do", &g.Block, "end"),
+		s("repeat", &g.Block, "until", &g.Exp),
+		s("if", &g.Exp, "then", &g.Block,
+			stream.Zom(s("elseif", &g.Exp, "then", &g.Block)),
+			stream.Opt(s("else", &g.Block)),
+			"end",
+		),
+		s("for", &g.Name, "=", &g.Exp, ",", &g.Exp, stream.Opt(s(",", &g.Exp)), "do", &g.Block, "end"),
+		s("for", &g.NameList, "in", &g.ExpList, "do", &g.Block, "end"),
+		s("function", &g.FuncName, &g.FuncBody),
+		s("local", "function", &g.Name, &g.FuncBody),
+		s("local", &g.AttNameList, stream.Opt(s("=", &g.ExpList))),
+		&g.FuncCall,
+	)}
+	g.Block = stream.Rule{Name: "block", Reader: s(stream.Zom(&g.Stat), stream.Opt(&g.RetStat))}
+	return g
+}
+
+func (r *LuaStreamReader) Read(s *stream.Stream) error {
+	err := r.Block.Read(s)
+	if err != nil {
+		return fmt.Errorf("lua parse error: %w", err)
+	}
+	return nil
+}
+
+func (r *LuaStreamReader) What() string {
+	return "lua"
+}
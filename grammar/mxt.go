@@ -51,7 +51,7 @@ func MXT() *MXTReader {
 func (r *MXTReader) Read(s *Scanner) error {
 	err := r.Chunks.Read(s)
 	if err != nil {
-		return fmt.Errorf("mxt parse error: %v", err)
+		return fmt.Errorf("mxt parse error: %w", err)
 	}
 	return nil
 }
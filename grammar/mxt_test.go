@@ -11,24 +11,23 @@ func TestMXTHeader(t *testing.T) {
 
 }
 
-func TestMXT(t *testing.T) {
-	posCases := []struct {
-		mxt     string
-		names   []string
-		content []string
-	}{
-		{
-			`// name.of.chunk -->`,
-			[]string{"name.of.chunk"},
-			[]string{""},
-		},
-		{
-			`//salt++++++++++++++++++++++++++ Σ-element ++++++++++++++++++++++++++++++++++-->`,
-			[]string{"Σ-element"},
-			[]string{""},
-		},
-		{
-			`//---------------------------------------------------------------- user.json -->
+var mxtCases = []struct {
+	mxt     string
+	names   []string
+	content []string
+}{
+	{
+		`// name.of.chunk -->`,
+		[]string{"name.of.chunk"},
+		[]string{""},
+	},
+	{
+		`//salt++++++++++++++++++++++++++ Σ-element ++++++++++++++++++++++++++++++++++-->`,
+		[]string{"Σ-element"},
+		[]string{""},
+	},
+	{
+		`//---------------------------------------------------------------- user.json -->
 {
     "user": "alucard",
     "password": "C:SotN1997"
@@ -75,20 +74,20 @@ int main(void) {
 printf("Hello World\n");
 return 0;
 }`,
-			[]string{"user.json", "connection.ini", "user.pgp", "hello-world.h", "hello-world.c"},
-			[]string{
-				`{
+		[]string{"user.json", "connection.ini", "user.pgp", "hello-world.h", "hello-world.c"},
+		[]string{
+			`{
     "user": "alucard",
     "password": "C:SotN1997"
 }`,
-				`request: GET
+			`request: GET
 
 [url]
 schema=http
 host=localhost
 port=8080
 path=/db/add`,
-				`-----BEGIN PGP MESSAGE-----
+			`-----BEGIN PGP MESSAGE-----
 
 hQEMA8p144+Gi+YpAQf/VeFG9Zb+8w9aldWll8n2g3jqpE613LKg2XAJgwXQmSQL
 R4O+TlQakJ+Mz5vM4IxxubPgYCyt6cyL7qM3oJIuk7vsqMbl5t7c/dOfXjj7goIC
@@ -107,18 +106,20 @@ ro/CU6vIqkOSNRUrNYDwqz1g3Z3eAQB/8t9Y4WsV4KL0M229rsFrtl26i7+quYfg
 uuTd
 =WxK9
 -----END PGP MESSAGE-----`,
-				``,
-				`// this is part of hello-world.c
+			``,
+			`// this is part of hello-world.c
 #include<stdio.h>
 
 int main(void) {
 printf("Hello World\n");
 return 0;
 }`,
-			},
 		},
-	}
-	for i, c := range posCases {
+	},
+}
+
+func TestMXT(t *testing.T) {
+	for i, c := range mxtCases {
 		sca := tok.NewScanner(c.mxt)
 		r := MXT()
 		names := []string{}
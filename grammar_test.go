@@ -2,6 +2,7 @@ package tok
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -50,3 +51,320 @@ func TestSetRuleNames(t *testing.T) {
 		}
 	}
 }
+
+type BaseGrammar struct {
+	Item Rule `name:"item"`
+	List Rule `name:"list"`
+	WS   Rule `name:"ws"`
+}
+
+func newBaseGrammar() *BaseGrammar {
+	g := &BaseGrammar{}
+	MustSetRuleNames(g)
+	g.WS.Reader = Zom(' ')
+	g.Item.Reader = Many(Between('a', 'z'))
+	g.List.Reader = Seq(&g.Item, Zom(Seq(&g.WS, ',', &g.WS, &g.Item)))
+	return g
+}
+
+func (g *BaseGrammar) Read(s *Scanner) error {
+	return g.List.Read(s)
+}
+
+func (g *BaseGrammar) What() string {
+	return "base"
+}
+
+func (g *BaseGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+type derivedGrammar struct {
+	*BaseGrammar
+	Comment Rule `name:"comment"`
+}
+
+func newDerivedGrammar() *derivedGrammar {
+	g := &derivedGrammar{BaseGrammar: newBaseGrammar()}
+	MustSetRuleNames(g)
+	g.Comment.Reader = Seq('#', Past('#'))
+	g.WS.Reader = Zom(Any(' ', &g.Comment))
+	return g
+}
+
+func (g *derivedGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestDerivedGrammar(t *testing.T) {
+	g := newDerivedGrammar()
+	names := []string{}
+	for _, r := range g.Grammar() {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, " ") != "item list ws comment" {
+		t.Errorf("unexpected rules: %v", names)
+	}
+	if err := CheckRules(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	sca := NewScanner("a #x# , b")
+	if err := sca.Use(g); err != nil || !sca.AtEnd() {
+		t.Errorf("derived grammar did not read the whole text: %v", err)
+	}
+	sca = NewScanner("a #x# , b")
+	if sca.Use(newBaseGrammar()); sca.AtEnd() {
+		t.Errorf("base grammar should not read comments")
+	}
+}
+
+type duplicateGrammar struct {
+	*BaseGrammar
+	Other Rule `name:"ws"`
+}
+
+func (g *duplicateGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestOverrideDuplicate(t *testing.T) {
+	g := &duplicateGrammar{BaseGrammar: newBaseGrammar()}
+	MustSetRuleNames(g)
+	g.Other.Reader = WS()
+	if err := CheckRules(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := Override(g, "ws", Zom(' '))
+	if err == nil || err.Error() != "the name ws is used by more than one rule" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if g.Other.Reader.What() != WS().What() {
+		t.Errorf("a failed Override should keep the Reader: %s", g.Other.Reader.What())
+	}
+}
+
+func TestOverride(t *testing.T) {
+	g := newBaseGrammar()
+	if err := Override(g, "item", Many(Digit())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewScanner("1 , 23").Use(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := Override(g, "missing", WS())
+	if err == nil || err.Error() != "base has no rule missing" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = Override(g, "ws", nil)
+	if err == nil || err.Error() != "the Reader of ws is a nil value" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckRules(g); err != nil {
+		t.Errorf("a failed Override should keep the Reader: %v", err)
+	}
+	if err := NewScanner("1 , 23").Use(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckRulesInvalid(t *testing.T) {
+	cases := []struct {
+		r   Reader
+		exp string
+	}{
+		{Repeat(3, 1, Digit()), "the Reader of item is invalid: min 3 is greater than max 1"},
+		{&RepeatReader{Min: 2, Max: 1}, "the Reader of item is invalid: min 2 is greater than max 1"},
+		{Seq('a', Opt(Repeat(2, 4, 1.5))), "the Reader of item is invalid: invalid Repeat parameter: unknown type float64"},
+		{Repeat(3, -1, Digit()), ""},
+	}
+	for i, c := range cases {
+		g := newBaseGrammar()
+		g.Item.Reader = c.r
+		err := CheckRules(g)
+		if c.exp == "" {
+			if err != nil {
+				t.Errorf("%d unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.exp {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+	}
+}
+
+type moduleGrammar struct {
+	Base      *BaseGrammar `name:"base"`
+	Pair      Template     `name:"pair"`
+	DigitPair Rule         `name:"digit-pair"`
+	ListPair  Rule         `name:"list-pair"`
+	Pairs     Rule         `name:"pairs"`
+}
+
+func newModuleGrammar() *moduleGrammar {
+	g := &moduleGrammar{Base: newBaseGrammar()}
+	MustSetRuleNames(g)
+	g.Pair.Params = []string{"key", "value"}
+	g.Pair.New = func(args ...Reader) Reader {
+		return Seq(args[0], '=', args[1])
+	}
+	g.DigitPair.Reader = g.Pair.Apply(&g.Base.Item, Digit())
+	g.ListPair.Reader = g.Pair.Apply(&g.Base.Item, &g.Base.List)
+	g.Pairs.Reader = Seq(&g.DigitPair, ';', &g.ListPair)
+	return g
+}
+
+func (g *moduleGrammar) Read(s *Scanner) error {
+	return g.Pairs.Read(s)
+}
+
+func (g *moduleGrammar) What() string {
+	return "module"
+}
+
+func (g *moduleGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestModuleGrammar(t *testing.T) {
+	g := newModuleGrammar()
+	lines := strings.Join(GrammarLines(g.Grammar()), "\n")
+	exp := strings.Join([]string{
+		"base.item: +<az>",
+		"base.list: base.item *base.ws ',' base.ws base.item",
+		"base.ws: *' '",
+		"digit-pair: base.item '=' <09>",
+		"list-pair: base.item '=' base.list",
+		"pairs: digit-pair ';' list-pair",
+	}, "\n")
+	if lines != exp {
+		t.Errorf("unexpected rules:\n%s\nexpected:\n%s", lines, exp)
+	}
+	if err := CheckRules(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := NewScanner("a=1;b=c , d").Use(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl := Template{
+		Name:   "many",
+		Params: []string{"item"},
+		New:    func(args ...Reader) Reader { return Many(args[0]) },
+	}
+	first := tmpl.Apply('x')
+	wrong := tmpl.Apply('x', 'y')
+	invalid := tmpl.Apply(1.5)
+	if tmpl.What() != "many(item)" {
+		t.Errorf("unexpected What: %s", tmpl.What())
+	}
+	if err := NewScanner("xx").Use(first); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !HasInvalidReader(wrong.What()) || !strings.Contains(wrong.What(), "invalid many(item) arguments: expects (item), got 2") {
+		t.Errorf("expected an invalid reader: %s", wrong.What())
+	}
+	if !HasInvalidReader(invalid.What()) || !strings.Contains(invalid.What(), "invalid many(item) parameter at 1: unknown type float64") {
+		t.Errorf("expected an invalid reader: %s", invalid.What())
+	}
+}
+
+func TestSetRuleNamesKind(t *testing.T) {
+	var nilGrammar *moduleGrammar
+	for _, g := range []interface{}{moduleGrammar{}, nilGrammar, new(int), nil} {
+		err := SetRuleNames(g)
+		if err == nil || !strings.HasPrefix(err.Error(), "SetRuleNames expects a pointer to a struct") {
+			t.Errorf("unexpected error for %T: %v", g, err)
+		}
+		if rules := CollectRules(g); len(rules) != 0 {
+			t.Errorf("unexpected rules for %T: %v", g, rules)
+		}
+	}
+}
+
+func TestWrapReaders(t *testing.T) {
+	digit := Rule{Name: "digit", Reader: Digit()}
+	shared := Any('a', &digit)
+	r := Seq(shared, Opt(SepBy(shared, Rune(','))))
+	names := []string{}
+	wrapped, unwalked := WrapReaders(r, func(sub Reader) Reader {
+		return Named("<"+sub.What()+">", Wrap(sub.What(), func(s *Scanner) error {
+			names = append(names, sub.What())
+			return sub.Read(s)
+		}))
+	})
+	if err := NewScanner("a1,a").Use(wrapped); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	exp := "[[ <'a'> digit ] 'a' ?<*([ 'a' digit ] % ',')> *([ 'a' digit ] % ',')]"
+	if fmt.Sprint(names) != exp {
+		t.Errorf("unexpected calls: %v", names)
+	}
+	if wrapped.What() != "<[ <'a'> digit ]> <?<*([ 'a' digit ] % ',')>>" {
+		t.Errorf("unexpected What: %s", wrapped.What())
+	}
+	if len(unwalked) != 1 || unwalked[0].What() != "*([ 'a' digit ] % ',')" {
+		t.Errorf("unexpected unwalked Readers: %v", unwalked)
+	}
+	names = names[:0]
+	if err := NewScanner("a1,a").Use(r); err != nil || len(names) != 0 {
+		t.Errorf("the shared Readers were changed: %v %v", err, names)
+	}
+
+	count := 0
+	counter := func(sub Reader) Reader {
+		return Wrap(sub.What(), func(s *Scanner) error {
+			count++
+			return sub.Read(s)
+		})
+	}
+	wrapped, unwalked = WrapReaders(Seq(&twiceReader{Rune('a')}, opaqueReader{Rune('b')}), counter)
+	if err := NewScanner("aab").Use(wrapped); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("unexpected number of calls: %d", count)
+	}
+	if len(unwalked) != 1 || unwalked[0].What() != "opaque" {
+		t.Errorf("unexpected unwalked Readers: %v", unwalked)
+	}
+}
+
+// twiceReader is a ParentReader of another package that reads sub twice.
+type twiceReader struct {
+	sub Reader
+}
+
+func (r *twiceReader) Read(s *Scanner) error {
+	if err := r.sub.Read(s); err != nil {
+		return err
+	}
+	return r.sub.Read(s)
+}
+
+func (r *twiceReader) What() string {
+	return "2*" + r.sub.What()
+}
+
+func (r *twiceReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *twiceReader) WithSubReaders(subs []Reader) Reader {
+	return &twiceReader{subs[0]}
+}
+
+// opaqueReader is a Reader of another package that does not implement ParentReader.
+type opaqueReader struct {
+	sub Reader
+}
+
+func (r opaqueReader) Read(s *Scanner) error {
+	return r.sub.Read(s)
+}
+
+func (r opaqueReader) What() string {
+	return "opaque"
+}
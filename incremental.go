@@ -0,0 +1,478 @@
+package tok
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+//------------------------------------------------------------------------------
+
+// Edit describes a change of a text, the sub string that Token marks will be replaced with Text.
+type Edit struct {
+	Token
+	Text string
+}
+
+// MakeEdit creates an Edit that replaces the text between a and b with text.
+func MakeEdit(a, b Marker, text string) Edit {
+	return Edit{MakeToken(a, b), text}
+}
+
+// Delta returns the difference in bytes between the length of the new and the old text.
+func (e Edit) Delta() int {
+	return len(e.Text) - e.Len()
+}
+
+// Apply returns str with the Edit e.
+// Returns an error if the Token of e is not inside of str.
+func (e Edit) Apply(str string) (string, error) {
+	if e.from < 0 || e.from > e.to || int(e.to) > len(str) {
+		return str, fmt.Errorf("edit %s is out of range [0-%d)", e.Token.String(), len(str))
+	}
+	return str[:e.from] + e.Text + str[e.to:], nil
+}
+
+//------------------------------------------------------------------------------
+
+// Incremental parses a text with a Grammar and reparses after an Edit only the damaged region.
+// Incremental reads with copies of the Rules of the Grammar, the Grammar itself is not changed.
+// Each copy picks the read Segments, the Graph gets built from the picked Segments of the current text.
+type Incremental struct {
+	Name      string
+	grammar   Grammar
+	tracker   *incTracker
+	rules     map[string]*Rule
+	copies    map[*Rule]*Rule
+	lookahead int
+	text      string
+	graph     *Graph
+	picks     []Segment
+	infos     []readInfo
+	edit      Edit
+	old       map[nodeKey]int
+}
+
+// readInfo stores how far the Readers have looked into the text around a picked Segment.
+type readInfo struct {
+	before Marker // end of the text that the Readers of the parent looked at before the Segment
+	reach  Marker // end of the text that the Readers of the Segment looked at
+	picks  int    // number of Segments that were picked while reading the Segment, including the Segment
+}
+
+// nodeKey identifies the Segment that a Rule has read from a Marker.
+type nodeKey struct {
+	name string
+	from Marker
+}
+
+// NewIncremental creates an Incremental parser for g, name will be used as the info of the root node.
+// Edit reuses the Nodes of the last parse only if all Rules of g use the Readers of this package and read forward.
+// Otherwise each Edit parses the full text.
+func NewIncremental(name string, g Grammar) *Incremental {
+	inc := &Incremental{
+		Name:    name,
+		grammar: g,
+		tracker: &incTracker{},
+		rules:   map[string]*Rule{},
+		copies:  map[*Rule]*Rule{},
+	}
+	for _, r := range g.Grammar() {
+		n := lookahead(r.Reader)
+		if n < 0 || inc.lookahead < 0 {
+			inc.lookahead = -1
+		} else if n > inc.lookahead {
+			inc.lookahead = n
+		}
+		c := &Rule{Name: r.Name, Reader: &incReader{inc, r.Name, n, r.Reader}}
+		inc.rules[r.Name] = c
+		inc.copies[r] = c
+	}
+	return inc
+}
+
+// Text returns the current text.
+func (inc *Incremental) Text() string {
+	return inc.text
+}
+
+// Graph returns the Graph of the current text, the value is nil if the last parse failed.
+func (inc *Incremental) Graph() *Graph {
+	return inc.graph
+}
+
+// Parse parses the full text.
+func (inc *Incremental) Parse(text string) (*Graph, error) {
+	inc.old = nil
+	return inc.parse(text)
+}
+
+// parse reads the full text with the Grammar and reuses the Segments in inc.old.
+func (inc *Incremental) parse(text string) (*Graph, error) {
+	sca := inc.scanner(text, 0)
+	err := sca.Use(inc.grammar)
+	inc.text = text
+	if err != nil {
+		inc.graph, inc.picks, inc.infos = nil, nil, nil
+		return nil, err
+	}
+	inc.setPicks(inc.tracker.Picked(), inc.tracker.infos)
+	return inc.graph, nil
+}
+
+// setPicks sets the picked Segments of the current text and builds the Graph.
+func (inc *Incremental) setPicks(picks []Segment, infos []readInfo) {
+	inc.picks, inc.infos = picks, infos
+	inc.graph = BuildGraph(inc.Name, picks)
+}
+
+// Edit applies e on the text and reparses the deepest Node that covers e.
+// Afterwards each ancestor of the Node gets reparsed until the Rule of an ancestor reads the same Segments as before,
+// apart from the reparsed Node. Edit stops only at ancestors whose parents did not look at e before they read them.
+// The full text will be parsed if no ancestor stops.
+// Each reparse reuses the Nodes that were read before e without looking at e and the Nodes that follow e.
+// Returns the updated Graph and the Tokens in the new text that were reparsed.
+// Returns an error without any change if e is out of range.
+func (inc *Incremental) Edit(e Edit) (*Graph, []Token, error) {
+	text, err := e.Apply(inc.text)
+	if err != nil {
+		return inc.graph, nil, err
+	}
+	full := []Token{MakeToken(0, Marker(len(text)))}
+	if inc.graph == nil || inc.lookahead < 0 {
+		g, err := inc.Parse(text)
+		return g, full, err
+	}
+
+	inc.edit = e
+	inc.old = map[nodeKey]int{}
+	for i, seg := range inc.picks {
+		inc.old[nodeKey{seg.Info, seg.from}] = i
+	}
+	defer func() {
+		inc.old = nil
+	}()
+
+	chain := inc.covering(e.Token)
+	isolated := 0
+	for isolated < len(chain) && inc.infos[chain[isolated]].before <= e.from {
+		isolated++
+	}
+	start := len(chain) - 1
+	if isolated == 0 {
+		start = -1
+	} else if isolated < start {
+		start = isolated
+	}
+	delta := Marker(e.Delta())
+	var child int
+	var sub []Segment
+	for i := start; i >= 0; i-- {
+		a := chain[i]
+		picks, infos, ok := inc.reparse(text, a)
+		if ok && sub != nil {
+			patched, _ := inc.replaced(inc.first(a), a, child, sub, nil, delta)
+			if equalSegments(picks, patched) {
+				infos[len(infos)-1].before = inc.infos[a].before
+				inc.setPicks(inc.replaced(0, len(inc.picks)-1, a, picks, infos, delta))
+				inc.text = text
+				return inc.graph, []Token{picks[len(picks)-1].Token}, nil
+			}
+		}
+		child, sub = a, nil
+		if ok {
+			sub = picks
+		}
+	}
+
+	g, err := inc.parse(text)
+	return g, full, err
+}
+
+// scanner creates a Scanner for text at m that reads with the Rule copies of inc.
+func (inc *Incremental) scanner(text string, m Marker) *Scanner {
+	sca := NewScanner(text)
+	sca.ToMarker(m)
+	inc.tracker.start(text, m, inc.lookahead)
+	sca.Tracker = inc.tracker
+	sca.rules = inc.copies
+	return sca
+}
+
+// covering returns the indices of the picked Segments from the top to the deepest Segment that covers t.
+// Each Segment was picked while its predecessor was read.
+func (inc *Incremental) covering(t Token) []int {
+	deepest := -1
+	for i, seg := range inc.picks {
+		if seg.Covers(t) && (deepest < 0 || inc.infos[i].picks < inc.infos[deepest].picks) {
+			deepest = i
+		}
+	}
+	if deepest < 0 {
+		return nil
+	}
+	chain := []int{deepest}
+	first := inc.first(deepest)
+	for i := deepest + 1; i < len(inc.picks); i++ {
+		if inc.first(i) <= first {
+			chain = append([]int{i}, chain...)
+		}
+	}
+	return chain
+}
+
+// first returns the index of the first Segment that was picked while the Segment at i was read.
+func (inc *Incremental) first(i int) int {
+	return i - inc.infos[i].picks + 1
+}
+
+// reparse reads the new text with the Rule of the Segment at i from the start of the Segment.
+// Returns the picked Segments, the last one is the Segment of the Rule.
+func (inc *Incremental) reparse(text string, i int) ([]Segment, []readInfo, bool) {
+	seg := inc.picks[i]
+	r, ok := inc.rules[seg.Info]
+	if !ok {
+		return nil, nil, false
+	}
+	sca := inc.scanner(text, seg.from)
+	if _, err := sca.TokenizeUse(r); err != nil {
+		return nil, nil, false
+	}
+	return inc.tracker.Picked(), inc.tracker.infos, true
+}
+
+// replaced returns the picked Segments from first to last,
+// where sub replaces the Segments that were picked while the Segment at old was read.
+// The Segments behind old are moved by delta, the Segments around old end delta later and contain sub.
+// infos replaces the readInfo values of old if it is not nil.
+func (inc *Incremental) replaced(first, last, old int, sub []Segment, infos []readInfo, delta Marker) ([]Segment, []readInfo) {
+	oldFirst := inc.first(old)
+	segs := append(append([]Segment{}, inc.picks[first:oldFirst]...), sub...)
+	var resInfos []readInfo
+	if infos != nil {
+		resInfos = append(append([]readInfo{}, inc.infos[first:oldFirst]...), infos...)
+	}
+	for j := old + 1; j <= last; j++ {
+		seg, info := inc.picks[j], inc.infos[j]
+		if inc.first(j) > oldFirst {
+			seg.from += delta
+			info.before += delta
+		} else {
+			info.picks += len(sub) - (old - oldFirst + 1)
+		}
+		seg.to += delta
+		info.reach += delta
+		segs = append(segs, seg)
+		if infos != nil {
+			resInfos = append(resInfos, info)
+		}
+	}
+	return segs, resInfos
+}
+
+// reusable returns the index of the Segment of the last parse that the Rule name would read from m in the new text,
+// and the delta to move the Segment into the new text.
+func (inc *Incremental) reusable(s *Scanner, name string, m Marker) (int, Marker, bool) {
+	if inc.old == nil || s.Reversed() {
+		return 0, 0, false
+	}
+	e := inc.edit
+	delta := Marker(e.Delta())
+	if m >= e.to+delta {
+		i, ok := inc.old[nodeKey{name, m - delta}]
+		if ok && inc.picks[i].from >= e.to && int(inc.picks[i].to+delta) <= s.end {
+			return i, delta, true
+		}
+		return 0, 0, false
+	}
+	i, ok := inc.old[nodeKey{name, m}]
+	if ok && inc.infos[i].reach <= e.from && int(inc.picks[i].to) <= s.end {
+		return i, 0, true
+	}
+	return 0, 0, false
+}
+
+// reuse moves s behind the Segment at i of the last parse
+// and picks the Segments that were picked while the Segment was read, moved by delta.
+func (inc *Incremental) reuse(s *Scanner, i int, delta Marker) {
+	t := inc.tracker
+	before := t.looked()
+	s.ToMarker(inc.picks[i].to + delta)
+	for j := inc.first(i); j <= i; j++ {
+		seg, info := inc.picks[j], inc.infos[j]
+		seg.from += delta
+		seg.to += delta
+		info.before += delta
+		info.reach += delta
+		if j == i {
+			info.before = before
+			if info.reach > t.reach {
+				t.reach = info.reach
+			}
+		}
+		t.add(seg, info)
+	}
+}
+
+func equalSegments(a, b []Segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lookahead returns how many runes the Readers of a Rule look at from the furthest Marker that the Scanner reached,
+// the Readers of other Rules are not counted.
+// The end of a Janus pair tells a lookTracker how far it looks, both Readers of the pair must belong to the Rule.
+// Returns -1 if the value is unknown, like for Readers that Wrap creates, or if a Reader reads in reverse direction.
+func lookahead(r Reader) int {
+	n := 0
+	pairs := map[*janusEndReader]int{}
+	walkReaders(r, func(r Reader) {
+		if n < 0 {
+			return
+		}
+		size := -1
+		switch r := r.(type) {
+		case *janusBeginReader:
+			pairs[r.end]++
+			size = 0
+		case *janusEndReader:
+			pairs[r]--
+			size = 0
+		case litReader:
+			size = utf8.RuneCountInString(r.str)
+		case foldReader:
+			size = utf8.RuneCountInString(r.val)
+		case *anyRuneReader, atEndReader, betweenReader, *betweenAnyReader, holeyReader, runeReader, invalidReader:
+			size = 1
+		case *behindReader, *notBehindReader, *revReader:
+		default:
+			if len(subReaders(r)) > 0 || isRule(r) {
+				size = 0
+			}
+		}
+		if size < 0 {
+			n = -1
+		} else if size > n {
+			n = size
+		}
+	})
+	for _, open := range pairs {
+		if open != 0 {
+			return -1
+		}
+	}
+	return n
+}
+
+func isRule(r Reader) bool {
+	_, ok := r.(*Rule)
+	return ok
+}
+
+//------------------------------------------------------------------------------
+
+// lookTracker is a Tracker that records how far a Reader looked at the text without moving the Scanner there.
+type lookTracker interface {
+	look(m Marker)
+}
+
+// incTracker picks the Segments for an Incremental parser and records how far the Readers looked into the text.
+// The Readers of a Rule look at most lookahead runes from the furthest Marker that the Scanner reached in the Rule.
+type incTracker struct {
+	Basket
+	infos     []readInfo
+	text      string
+	pos       Marker
+	lookahead int
+	reach     Marker
+}
+
+// start resets t for a Scanner that reads text from m.
+func (t *incTracker) start(text string, m Marker, lookahead int) {
+	t.Reset()
+	t.infos = []readInfo{}
+	t.text = text
+	t.pos = m
+	t.lookahead = lookahead
+	t.reach = m
+}
+
+// look records that a Reader looked at the text up to m without moving the Scanner there.
+func (t *incTracker) look(m Marker) {
+	if m > t.reach {
+		t.reach = m
+	}
+}
+
+// add picks seg with info.
+func (t *incTracker) add(seg Segment, info readInfo) {
+	t.Add(seg)
+	t.infos = append(t.infos, info)
+}
+
+func (t *incTracker) Update(m Marker) {
+	if m > t.pos {
+		t.pos = m
+	}
+	t.Basket.Update(m)
+	t.infos = t.infos[:len(t.segments)]
+}
+
+// looked returns the end of the text that the Readers have looked at, the end of the text counts as one byte.
+func (t *incTracker) looked() Marker {
+	p := int(t.pos)
+	for i := 0; i < t.lookahead; i++ {
+		if p >= len(t.text) {
+			p++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(t.text[p:])
+		p += size
+	}
+	if Marker(p) < t.reach {
+		return t.reach
+	}
+	return Marker(p)
+}
+
+// incReader reads with the Reader of a Rule for an Incremental parser.
+// The Reader picks the read Segment and reuses the Segments of the last parse that an Edit does not affect.
+type incReader struct {
+	inc       *Incremental
+	name      string
+	lookahead int
+	sub       Reader
+}
+
+func (r *incReader) Read(s *Scanner) error {
+	inc, t := r.inc, r.inc.tracker
+	m := s.Mark()
+	if i, delta, ok := inc.reusable(s, r.name, m); ok {
+		inc.reuse(s, i, delta)
+		t.pos = s.Mark()
+		return nil
+	}
+	before, lookahead, n := t.looked(), t.lookahead, len(t.segments)
+	t.pos, t.lookahead, t.reach = m, r.lookahead, m
+	tok, err := s.TokenizeUse(r.sub)
+	reach := t.looked()
+	t.pos, t.lookahead, t.reach = s.Mark(), lookahead, before
+	if reach > before {
+		t.reach = reach
+	}
+	if err != nil {
+		return err
+	}
+	t.add(Segment{r.name, tok}, readInfo{before, reach, len(t.segments) - n + 1})
+	return nil
+}
+
+func (r *incReader) What() string {
+	return r.sub.What()
+}
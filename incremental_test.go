@@ -0,0 +1,257 @@
+package tok
+
+import (
+	"strings"
+	"testing"
+)
+
+type listGrammar struct {
+	List   Rule `name:"list"`
+	Item   Rule `name:"item"`
+	Word   Rule `name:"word"`
+	Number Rule `name:"number"`
+}
+
+func newListGrammar() *listGrammar {
+	g := &listGrammar{}
+	MustSetRuleNames(g)
+	g.Word.Reader = Many(Between('a', 'z'))
+	g.Number.Reader = Many(Digit())
+	g.Item.Reader = Any(&g.Word, &g.Number, &g.List)
+	g.List.Reader = Seq('(', SepBy(&g.Item, Rune(' ')), ')')
+	return g
+}
+
+func (g *listGrammar) Read(s *Scanner) error {
+	return g.List.Read(s)
+}
+
+func (g *listGrammar) What() string {
+	return "list"
+}
+
+func (g *listGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestIncremental(t *testing.T) {
+	cases := []struct {
+		edit    Edit
+		changed string
+	}{
+		{MakeEdit(3, 3, "x"), "[1-5)"},
+		{MakeEdit(11, 13, "123"), "[6-15)"},
+		{MakeEdit(6, 15, "(a)"), "[0-12)"},
+		{MakeEdit(3, 4, ""), "[1-4)"},
+		{MakeEdit(0, 1, "x"), "[0-11)"},
+		{MakeEdit(0, 1, "("), "[0-11)"},
+	}
+	inp := "(abc (def 42) g)"
+	inc := NewIncremental("test", newListGrammar())
+	if _, err := inc.Parse(inp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, c := range cases {
+		inp, _ = c.edit.Apply(inp)
+		g, changed, err := inc.Edit(c.edit)
+		if inc.Text() != inp {
+			t.Errorf("%d unexpected text: %q != %q", i, inc.Text(), inp)
+		}
+		if len(changed) != 1 || changed[0].String() != c.changed {
+			t.Errorf("%d unexpected changed ranges: %v", i, changed)
+		}
+		full := NewIncremental("test", newListGrammar())
+		exp, expErr := full.Parse(inp)
+		if (err == nil) != (expErr == nil) {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if err == nil && !g.Equal(exp) {
+			t.Errorf("%d unexpected graph:\n%s!=\n%s", i, g.FlameStack(), exp.FlameStack())
+		}
+	}
+}
+
+func TestIncrementalReuse(t *testing.T) {
+	g := newListGrammar()
+	l := MonitorGrammar(g)
+	words := strings.Repeat("abc ", 50)
+	inp := "(" + words + "(x 1) " + words + "z)"
+	inc := NewIncremental("test", g)
+	if _, err := inc.Parse(inp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, e := range []Edit{MakeEdit(203, 203, "yz"), MakeEdit(1, 1, "x"), MakeEdit(207, 208, "(2)")} {
+		l.Reset()
+		g, _, err := inc.Edit(e)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if len(l.Entries) > 20 {
+			t.Errorf("%d unexpected number of Rule reads: %d", i, len(l.Entries))
+		}
+		exp, err := NewIncremental("test", newListGrammar()).Parse(inc.Text())
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if !g.Equal(exp) {
+			t.Errorf("%d unexpected graph:\n%s!=\n%s", i, g.FlameStack(), exp.FlameStack())
+		}
+	}
+}
+
+func TestIncrementalKeepsGrammar(t *testing.T) {
+	g := newListGrammar()
+	readers := map[*Rule]Reader{}
+	for _, r := range g.Grammar() {
+		readers[r] = r.Reader
+	}
+	inc := NewIncremental("test", g)
+	if _, err := inc.Parse("(a (b 1))"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := inc.Edit(MakeEdit(4, 5, "c")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for r, reader := range readers {
+		if r.Reader != reader {
+			t.Errorf("unexpected change of rule %s", r.Name)
+		}
+	}
+	sca := NewScanner("(a (b 1))")
+	basket := sca.NewBasket()
+	if err := sca.Use(g); err != nil || len(basket.Picked()) != 0 {
+		t.Errorf("unexpected read of grammar: %v %v", err, basket)
+	}
+}
+
+type ancestorGrammar struct {
+	Program Rule `name:"program"`
+	Block   Rule `name:"block"`
+	Stat    Rule `name:"stat"`
+	Group   Rule `name:"group"`
+	Atom    Rule `name:"atom"`
+	Word    Rule `name:"word"`
+}
+
+func newAncestorGrammar() *ancestorGrammar {
+	g := &ancestorGrammar{}
+	MustSetRuleNames(g)
+	g.Word.Reader = Many(Between('a', 'z'))
+	g.Atom.Reader = Any(&g.Word, Many(Digit()))
+	g.Group.Reader = Seq('(', &g.Atom, ')')
+	g.Stat.Reader = Any(Seq(Lit("(ab)"), '!'), Seq(&g.Group, '!'))
+	g.Block.Reader = Seq('{', SepBy(&g.Stat, ' '), '}')
+	g.Program.Reader = Seq(SepBy(&g.Block, ' '), AtEnd())
+	return g
+}
+
+func (g *ancestorGrammar) Read(s *Scanner) error {
+	return g.Program.Read(s)
+}
+
+func (g *ancestorGrammar) What() string {
+	return "stats"
+}
+
+func (g *ancestorGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestIncrementalAncestor(t *testing.T) {
+	cases := []struct {
+		edit    Edit
+		changed string
+	}{
+		{MakeEdit(15, 16, "b"), "[7-24)"},
+		{MakeEdit(15, 16, "x"), "[7-24)"},
+		{MakeEdit(9, 10, "y"), "[8-12)"},
+		{MakeEdit(27, 28, "ab"), "[25-32)"},
+	}
+	inp := "{(z)!} {(x)! (ax)! (z)!} {(y)!}"
+	inc := NewIncremental("test", newAncestorGrammar())
+	if _, err := inc.Parse(inp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, c := range cases {
+		g, changed, err := inc.Edit(c.edit)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if len(changed) != 1 || changed[0].String() != c.changed {
+			t.Errorf("%d unexpected changed ranges: %v", i, changed)
+		}
+		exp, err := NewIncremental("test", newAncestorGrammar()).Parse(inc.Text())
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if !g.Equal(exp) {
+			t.Errorf("%d unexpected graph:\n%s!=\n%s", i, g.FlameStack(), exp.FlameStack())
+		}
+	}
+}
+
+type choiceGrammar struct {
+	List   Rule `name:"list"`
+	Item   Rule `name:"item"`
+	Number Rule `name:"number"`
+	Word   Rule `name:"word"`
+}
+
+func newChoiceGrammar() *choiceGrammar {
+	g := &choiceGrammar{}
+	MustSetRuleNames(g)
+	g.Number.Reader = Many(Digit())
+	g.Word.Reader = Many(Set("a-z0-9", ""))
+	g.Item.Reader = Any(&g.Number, &g.Word)
+	g.List.Reader = Seq('(', SepBy(&g.Item, Rune(' ')), ')')
+	return g
+}
+
+func (g *choiceGrammar) Read(s *Scanner) error {
+	return g.List.Read(s)
+}
+
+func (g *choiceGrammar) What() string {
+	return "list"
+}
+
+func (g *choiceGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestIncrementalChoice(t *testing.T) {
+	inc := NewIncremental("test", newChoiceGrammar())
+	if _, err := inc.Parse("(a1 b)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g, _, err := inc.Edit(MakeEdit(1, 2, "2"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp, err := NewIncremental("test", newChoiceGrammar()).Parse("(21 b)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !g.Equal(exp) {
+		t.Errorf("unexpected graph:\n%s!=\n%s", g.FlameStack(), exp.FlameStack())
+	}
+}
+
+func TestEditOutOfRange(t *testing.T) {
+	inc := NewIncremental("test", newListGrammar())
+	if _, err := inc.Parse("(a b)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, e := range []Edit{MakeEdit(3, 9, "x"), {Token{4, 2}, "x"}, MakeEdit(6, 6, "x")} {
+		if _, err := e.Apply(inc.Text()); err == nil {
+			t.Errorf("%d expected an error", i)
+		}
+		if _, _, err := inc.Edit(e); err == nil {
+			t.Errorf("%d expected an error", i)
+		}
+		if inc.Text() != "(a b)" || inc.Graph() == nil {
+			t.Errorf("%d unexpected change: %q", i, inc.Text())
+		}
+	}
+}
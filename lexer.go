@@ -0,0 +1,257 @@
+package tok
+
+import (
+	"fmt"
+	"io"
+)
+
+// LexRule describes a kind of token that a Lexer reads.
+// A LexRule can push a mode on the mode stack of the Lexer or pop the current mode.
+type LexRule struct {
+	Name   string
+	Reader Reader
+	Skip   bool
+	Push   string
+	Pop    bool
+}
+
+// Lex creates a LexRule for tokens with the name that r reads.
+// The type of r can be rune, string or Reader.
+func Lex(name string, r interface{}) LexRule {
+	sub, ok := asReader(r)
+	if !ok {
+		sub = InvalidReader("invalid Lex parameter: unknown type %T", r)
+	}
+	return LexRule{Name: name, Reader: sub}
+}
+
+// LexSkip creates a LexRule for tokens that the Lexer reads but skips, like whitespaces or comments.
+// The type of r can be rune, string or Reader.
+func LexSkip(name string, r interface{}) LexRule {
+	rule := Lex(name, r)
+	rule.Skip = true
+	return rule
+}
+
+// Pushes returns a copy of r that pushes mode after a token was read.
+func (r LexRule) Pushes(mode string) LexRule {
+	r.Push = mode
+	return r
+}
+
+// Pops returns a copy of r that pops the current mode after a token was read.
+func (r LexRule) Pops() LexRule {
+	r.Pop = true
+	return r
+}
+
+// Rule returns a readable representation of a LexRule.
+func (r LexRule) Rule() string {
+	str := r.Name + ": "
+	if r.Skip {
+		str += "~"
+	}
+	str += r.Reader.What()
+	if r.Pop {
+		str += " <<"
+	}
+	if r.Push != "" {
+		str += " >>" + r.Push
+	}
+	return str
+}
+
+//------------------------------------------------------------------------------
+
+type lexMode struct {
+	name  string
+	rules []LexRule
+}
+
+// Lexer splits the text of a Scanner into Segments.
+// Each Segment has the Name of the LexRule that read it as Info.
+// Segments that were read in a mode have an Info like "mode.name".
+type Lexer struct {
+	Rules []LexRule
+	modes []lexMode
+}
+
+// NewLexer creates a Lexer with the rules for the default mode.
+func NewLexer(rules ...LexRule) *Lexer {
+	return &Lexer{Rules: rules}
+}
+
+// Mode adds a mode with the rules to the Lexer.
+// Returns an error if name is not a valid rule name or the Lexer has already a mode with the name.
+func (l *Lexer) Mode(name string, rules ...LexRule) error {
+	if err := CheckRuleName(name); err != nil {
+		return fmt.Errorf("invalid lexer mode name %q: %w", name, err)
+	}
+	if _, err := l.rules(name); err == nil {
+		return fmt.Errorf("lexer mode %q is defined twice", name)
+	}
+	l.modes = append(l.modes, lexMode{name, rules})
+	return nil
+}
+
+// MustMode panics if an error occurs during Mode.
+func (l *Lexer) MustMode(name string, rules ...LexRule) *Lexer {
+	if err := l.Mode(name, rules...); err != nil {
+		panic(err)
+	}
+	return l
+}
+
+func (l *Lexer) rules(mode string) ([]LexRule, error) {
+	if mode == "" {
+		return l.Rules, nil
+	}
+	for _, m := range l.modes {
+		if m.name == mode {
+			return m.rules, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown lexer mode %q", mode)
+}
+
+// longest returns the index of the rule that reads the longest token and the Token.
+// Rules earlier in the list win if two rules read a token with the same length.
+func longest(s *Scanner, rules []LexRule) (int, Token) {
+	m := s.Mark()
+	idx := -1
+	var best Token
+	for i, r := range rules {
+		t, err := s.TokenizeUse(r.Reader)
+		s.ToMarker(m)
+		if err == nil && t.Len() > best.Len() {
+			idx, best = i, t
+		}
+	}
+	return idx, best
+}
+
+// Scan creates a LexScan that reads the tokens of s, starting in the default mode.
+func (l *Lexer) Scan(s *Scanner) *LexScan {
+	return &LexScan{lexer: l, sca: s}
+}
+
+// Next reads the next token that is not skipped from s in the default mode.
+// Returns io.EOF if s reached the end.
+// Use Scan to keep the modes between two tokens.
+func (l *Lexer) Next(s *Scanner) (Segment, error) {
+	return l.Scan(s).Next()
+}
+
+// Tokenize starts in the default mode and reads all tokens that are not skipped from s.
+// The scanner is only moved if no error occurs.
+func (l *Lexer) Tokenize(s *Scanner) ([]Segment, error) {
+	return l.Scan(s).Tokenize()
+}
+
+//------------------------------------------------------------------------------
+
+// LexScan holds the mode stack of a Lexer while it reads the tokens of one Scanner.
+// A Lexer can be used by several LexScans at the same time.
+type LexScan struct {
+	lexer  *Lexer
+	sca    *Scanner
+	stack  []string
+	basket *Basket
+}
+
+// Pick appends each Segment that ls reads to the Basket.
+func (ls *LexScan) Pick(b *Basket) {
+	ls.basket = b
+}
+
+// CurrentMode returns the name of the current mode, the default mode has an empty name.
+func (ls *LexScan) CurrentMode() string {
+	if len(ls.stack) == 0 {
+		return ""
+	}
+	return ls.stack[len(ls.stack)-1]
+}
+
+// Reset sets ls back to the default mode.
+func (ls *LexScan) Reset() {
+	ls.stack = nil
+}
+
+// Next reads the next token that is not skipped.
+// Returns io.EOF if the scanner reached the end.
+func (ls *LexScan) Next() (Segment, error) {
+	s := ls.sca
+	for !s.AtEnd() {
+		mode := ls.CurrentMode()
+		rules, err := ls.lexer.rules(mode)
+		if err != nil {
+			return Segment{}, err
+		}
+		i, t := longest(s, rules)
+		if i == -1 {
+			return Segment{}, s.ErrorFor("token")
+		}
+		r := rules[i]
+		if r.Pop && len(ls.stack) == 0 {
+			return Segment{}, fmt.Errorf("lexer rule %s pops the default mode at %d", r.Name, s.Mark())
+		}
+		if r.Push != "" {
+			if _, err := ls.lexer.rules(r.Push); err != nil {
+				return Segment{}, fmt.Errorf("lexer rule %s pushes at %d: %w", r.Name, s.Mark(), err)
+			}
+		}
+		s.ToMarker(t.to)
+		if r.Pop {
+			ls.stack = ls.stack[:len(ls.stack)-1]
+		}
+		if r.Push != "" {
+			ls.stack = append(ls.stack, r.Push)
+		}
+		if !r.Skip {
+			seg := Segment{r.Name, t}
+			if mode != "" {
+				seg.Info = mode + "." + r.Name
+			}
+			if ls.basket != nil {
+				ls.basket.Add(seg)
+			}
+			return seg, nil
+		}
+	}
+	return Segment{}, io.EOF
+}
+
+// Tokenize reads all following tokens that are not skipped.
+// The scanner and the mode stack are only changed if no error occurs.
+func (ls *LexScan) Tokenize() ([]Segment, error) {
+	s := ls.sca
+	m := s.Mark()
+	stack := append([]string{}, ls.stack...)
+	segs := []Segment{}
+	for {
+		seg, err := ls.Next()
+		if err == io.EOF {
+			return segs, nil
+		} else if err != nil {
+			s.ToMarker(m)
+			ls.stack = stack
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+}
+
+// Lines calls the Rule function on all rules and returns the result.
+// The rules of a mode have the mode name as prefix.
+func (l *Lexer) Lines() []string {
+	res := []string{}
+	for _, r := range l.Rules {
+		res = append(res, r.Rule())
+	}
+	for _, m := range l.modes {
+		for _, r := range m.rules {
+			res = append(res, m.name+"."+r.Rule())
+		}
+	}
+	return res
+}
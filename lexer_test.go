@@ -0,0 +1,146 @@
+package tok
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	l := NewLexer(
+		LexSkip("ws", Many(WS())),
+		Lex("keyword", Any("if", "then", "end")),
+		Lex("name", Many(BetweenAny("a-z"))),
+		Lex("op", Any("==", "=")),
+	)
+	sca := NewScanner("if ifx == a then end")
+	segs, err := l.Tokenize(sca)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	exp := []string{"keyword[0-2)", "name[3-6)", "op[7-9)", "name[10-11)", "keyword[12-16)", "keyword[17-20)"}
+	if len(segs) != len(exp) {
+		t.Fatalf("unexpected number of segments: %v", segs)
+	}
+	for i, seg := range segs {
+		if seg.String() != exp[i] {
+			t.Errorf("%d unexpected segment: %s != %s", i, seg.String(), exp[i])
+		}
+	}
+	if _, err = l.Next(sca); err != io.EOF {
+		t.Errorf("expected io.EOF: %v", err)
+	}
+
+	sca = NewScanner("a = 1")
+	_, err = l.Tokenize(sca)
+	if re, ok := err.(ReadError); !ok || re.Marker != 4 {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !sca.AtStart() {
+		t.Errorf("scanner was moved")
+	}
+}
+
+func TestLexerModes(t *testing.T) {
+	l := NewLexer(
+		LexSkip("ws", Many(WS())),
+		Lex("name", Many(BetweenAny("a-z"))),
+		Lex("op", Any("=", "+")),
+		Lex("quote", '"').Pushes("str"),
+	)
+	l.MustMode("str",
+		Lex("text", Many(Not(Any(Rune('"'), Lit("${"), AtEnd())))),
+		Lex("open", "${").Pushes("interp"),
+		Lex("quote", '"').Pops(),
+	)
+	l.MustMode("interp",
+		LexSkip("ws", Many(WS())),
+		Lex("name", Many(BetweenAny("a-z"))),
+		Lex("close", '}').Pops(),
+	)
+
+	sca := NewScanner(`x = "a ${ b } c" + y`)
+	basket := sca.NewBasket()
+	ls := l.Scan(sca)
+	ls.Pick(basket)
+	segs, err := ls.Tokenize()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	exp := "name[0-1);op[2-3);quote[4-5);str.text[5-7);str.open[7-9);interp.name[10-11);interp.close[12-13);str.text[13-15);str.quote[15-16);op[17-18);name[19-20)"
+	if basket.String() != exp {
+		t.Errorf("unexpected picked values: %s", basket.String())
+	}
+	if len(segs) != 11 {
+		t.Errorf("unexpected number of segments: %d", len(segs))
+	}
+	if ls.CurrentMode() != "" {
+		t.Errorf("unexpected mode: %q", ls.CurrentMode())
+	}
+
+	ls = l.Scan(NewScanner(`"abc`))
+	_, err = ls.Tokenize()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if ls.CurrentMode() != "str" {
+		t.Errorf("unexpected mode: %q", ls.CurrentMode())
+	}
+
+	a := l.Scan(NewScanner(`"a"`))
+	b := l.Scan(NewScanner(`b "c"`))
+	order := []*LexScan{a, b, b, a, b, a}
+	infos := []string{}
+	for _, ls := range order {
+		seg, err := ls.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		infos = append(infos, seg.Info)
+	}
+	if fmt.Sprint(infos) != "[quote name quote str.text str.text str.quote]" {
+		t.Errorf("modes of the scans are mixed: %v", infos)
+	}
+}
+
+func TestLexerModeErrors(t *testing.T) {
+	l := NewLexer(
+		Lex("open", '(').Pushes("group"),
+		Lex("close", ')').Pops(),
+		Lex("tag", '<').Pushes("tag"),
+	)
+	for i, name := range []string{"", "1st", "-x"} {
+		if err := l.Mode(name, Lex("x", 'x')); err == nil {
+			t.Errorf("%d expected an error for mode %q", i, name)
+		}
+	}
+	if err := l.Mode("group", Lex("close", ')').Pops()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := l.Mode("group", Lex("x", 'x')); err == nil {
+		t.Errorf("expected an error for a second group mode")
+	}
+
+	cases := []struct {
+		inp string
+		err string
+	}{
+		{"()", ""},
+		{")", "lexer rule close pops the default mode at 0"},
+		{"<", `lexer rule tag pushes at 0: unknown lexer mode "tag"`},
+		{"())", "lexer rule close pops the default mode at 2"},
+	}
+	for i, c := range cases {
+		sca := NewScanner(c.inp)
+		ls := l.Scan(sca)
+		_, err := ls.Tokenize()
+		if c.err == "" && err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		} else if c.err != "" && (err == nil || err.Error() != c.err) {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+		if c.err != "" && (!sca.AtStart() || ls.CurrentMode() != "") {
+			t.Errorf("%d lexer was moved: %q %q", i, sca.Tail(), ls.CurrentMode())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tok
+
+import "context"
+
+// Limits describes the safety limits for the Readers that read from a Scanner.
+// A zero value disables the corresponding limit.
+type Limits struct {
+	// MaxDepth limits the number of nested Rules.
+	MaxDepth int
+	// MaxSteps limits the number of Rule calls and scanner moves.
+	MaxSteps int
+	// MaxBacktrack limits the number of bytes the scanner moves against the reading direction.
+	MaxBacktrack int
+}
+
+type limiter struct {
+	Limits
+	ctx       context.Context
+	depth     int
+	steps     int
+	backtrack int
+	err       error
+	uses      int
+}
+
+// contextCheckInterval defines after how many steps the context will be checked.
+const contextCheckInterval = 256
+
+func (l *limiter) exceeded(s *Scanner, limit string, value int) error {
+	if l.err == nil {
+		l.err = LimitError{s.Mark(), limit, value}
+	}
+	return l.err
+}
+
+func (l *limiter) step(s *Scanner) error {
+	l.steps++
+	if l.MaxSteps > 0 && l.steps > l.MaxSteps {
+		return l.exceeded(s, "steps", l.MaxSteps)
+	}
+	if l.ctx != nil && l.err == nil && l.steps%contextCheckInterval == 0 {
+		if err := l.ctx.Err(); err != nil {
+			l.err = ContextError{s.Mark(), err}
+		}
+	}
+	return l.err
+}
+
+func (l *limiter) moved(s *Scanner, from int) {
+	l.step(s)
+	back := from - s.pos
+	if s.rev {
+		back = -back
+	}
+	if back > 0 {
+		l.backtrack += back
+		if l.MaxBacktrack > 0 && l.backtrack > l.MaxBacktrack {
+			l.exceeded(s, "backtrack", l.MaxBacktrack)
+		}
+	}
+}
+
+// SetLimits sets the safety limits for the Readers that read from s and resets all counters.
+// If a limit is exceeded fails each following Rule and move of s, Use returns a LimitError.
+// Each Use that is not called by a Reader starts with new counters, the limits apply per Use.
+func (s *Scanner) SetLimits(l Limits) {
+	s.limits = &limiter{Limits: l}
+}
+
+// Limits returns the safety limits of s.
+func (s *Scanner) Limits() Limits {
+	if s.limits == nil {
+		return Limits{}
+	}
+	return s.limits.Limits
+}
+
+// enterRule must be called if a Rule starts to read from s.
+func (s *Scanner) enterRule() error {
+	if s.limits == nil {
+		return nil
+	}
+	l := s.limits
+	l.depth++
+	if l.MaxDepth > 0 && l.depth > l.MaxDepth {
+		return l.exceeded(s, "depth", l.MaxDepth)
+	}
+	return l.step(s)
+}
+
+// exitRule must be called if a Rule ends to read from s, also if enterRule returns an error.
+func (s *Scanner) exitRule() {
+	if s.limits != nil {
+		s.limits.depth--
+	}
+}
+
+// halted returns true if a limit was exceeded or the context is done, Move fails afterwards.
+func (s *Scanner) halted() bool {
+	return s.limits != nil && s.limits.err != nil
+}
+
+// read calls f like Use, a Use that is not called by a Reader resets the counters and the error of the limits first.
+// Returns the LimitError or ContextError if a limit was exceeded or the context is done, otherwise the error of f.
+func (s *Scanner) read(f ReadFunc) error {
+	l := s.limits
+	if l == nil {
+		return f(s)
+	}
+	if l.uses == 0 {
+		l.depth, l.steps, l.backtrack, l.err = 0, 0, 0, nil
+	}
+	l.uses++
+	defer func() { l.uses-- }()
+	if err := f(s); l.err == nil {
+		return err
+	}
+	return l.err
+}
+
+// UseContext uses r on the scanner and checks periodically if ctx is done.
+// If ctx is done fails each following Rule and move of s, UseContext returns a ContextError.
+// The scanner is only moved if no error occurs.
+func (s *Scanner) UseContext(ctx context.Context, r Reader) error {
+	if err := ctx.Err(); err != nil {
+		return ContextError{s.Mark(), err}
+	}
+	l := s.limits
+	if l == nil {
+		s.limits = &limiter{}
+		defer func() { s.limits = nil }()
+	}
+	bkp := s.limits.ctx
+	s.limits.ctx = ctx
+	err := s.Use(r)
+	s.limits.ctx = bkp
+	if _, ok := err.(ContextError); ok {
+		s.limits.err = nil
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package tok
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nestedGrammar struct {
+	List Rule `name:"list"`
+}
+
+func newNestedGrammar() *nestedGrammar {
+	g := &nestedGrammar{}
+	MustSetRuleNames(g)
+	g.List.Reader = Seq('[', Opt(&g.List), ']')
+	return g
+}
+
+func (g *nestedGrammar) Read(s *Scanner) error {
+	return g.List.Read(s)
+}
+
+func (g *nestedGrammar) What() string {
+	return "nested"
+}
+
+func (g *nestedGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func TestLimits(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("[", n) + strings.Repeat("]", n)
+	}
+	cases := []struct {
+		inp    string
+		r      Reader
+		limits Limits
+		limit  string
+	}{
+		{nested(9), newNestedGrammar(), Limits{MaxDepth: 10}, ""},
+		{nested(11), newNestedGrammar(), Limits{MaxDepth: 10}, "depth"},
+		{nested(100000), newNestedGrammar(), Limits{MaxDepth: 1000}, "depth"},
+		{nested(10), newNestedGrammar(), Limits{MaxSteps: 15}, "steps"},
+		{strings.Repeat("a", 100) + "b", Any(Seq(Many('a'), 'c'), Seq(Many('a'), 'b')), Limits{MaxBacktrack: 100}, ""},
+		{strings.Repeat("a", 100) + "b", Any(Seq(Many('a'), 'c'), Seq(Many('a'), 'b')), Limits{MaxBacktrack: 99}, "backtrack"},
+	}
+	for i, c := range cases {
+		sca := NewScanner(c.inp)
+		sca.SetLimits(c.limits)
+		err := sca.Use(c.r)
+		if c.limit == "" {
+			if err != nil {
+				t.Errorf("%d unexpected error: %v", i, err)
+			}
+			continue
+		}
+		le, ok := err.(LimitError)
+		if !ok {
+			t.Errorf("%d expected a LimitError: %v", i, err)
+			continue
+		}
+		if le.Limit != c.limit {
+			t.Errorf("%d unexpected limit: %q != %q", i, le.Limit, c.limit)
+		}
+		if !sca.AtStart() {
+			t.Errorf("%d scanner was not restored", i)
+		}
+	}
+}
+
+func TestLimitsLoop(t *testing.T) {
+	// Past tries Many at each position, without the limit this needs about n*n/2 steps
+	sca := NewScanner(strings.Repeat("a", 20000))
+	sca.SetLimits(Limits{MaxSteps: 100})
+	start := time.Now()
+	err := sca.Use(Past(Seq(Many('a'), 'b')))
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("the limit did not stop the loop, took %v", d)
+	}
+	if le, ok := err.(LimitError); !ok || le.Limit != "steps" {
+		t.Errorf("expected a steps LimitError: %v", err)
+	}
+	if sca.limits.steps > 200 {
+		t.Errorf("unexpected number of steps: %d", sca.limits.steps)
+	}
+	if !sca.AtStart() {
+		t.Errorf("scanner was not restored")
+	}
+}
+
+func TestUseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	r := Many(Seq(Wrap("cancel", func(s *Scanner) error {
+		n++
+		if n == 1000 {
+			cancel()
+		}
+		return nil
+	}), 'a'))
+	sca := NewScanner(strings.Repeat("a", 10000))
+	err := sca.UseContext(ctx, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error: %v", err)
+	}
+	if _, ok := err.(ContextError); !ok {
+		t.Errorf("expected a ContextError: %v", err)
+	}
+	if !sca.AtStart() {
+		t.Errorf("scanner was not restored")
+	}
+
+	err = sca.UseContext(context.Background(), r)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !sca.AtEnd() {
+		t.Errorf("did not read the whole input")
+	}
+
+	err = sca.UseContext(ctx, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error: %v", err)
+	}
+}
+
+func TestUseContextPast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelOnce := Wrap("cancel", func(s *Scanner) error {
+		cancel()
+		return nil
+	})
+	sca := NewScanner(strings.Repeat("a", 20000))
+	start := time.Now()
+	err := sca.UseContext(ctx, Seq(cancelOnce, Past(Seq(Many('a'), 'b'))))
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("the canceled context did not stop the loop, took %v", d)
+	}
+	if _, ok := err.(ContextError); !ok || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled ContextError: %v", err)
+	}
+	if !sca.AtStart() {
+		t.Errorf("scanner was not restored")
+	}
+}
+
+func TestLimitsDepthAfterPanic(t *testing.T) {
+	sca := NewScanner("ab")
+	sca.SetLimits(Limits{MaxDepth: 2})
+	inner := Rule{Name: "inner", Reader: Wrap("panic", func(s *Scanner) error {
+		panic("stop")
+	})}
+	outer := Rule{Name: "outer", Reader: &inner}
+	func() {
+		defer func() { recover() }()
+		sca.Use(&outer)
+	}()
+	if sca.limits.depth != 0 {
+		t.Errorf("unexpected depth after a panic: %d", sca.limits.depth)
+	}
+}
+
+func TestLimitsPerUse(t *testing.T) {
+	sca := NewScanner("[[[]]][]")
+	sca.SetLimits(Limits{MaxDepth: 2, MaxSteps: 12})
+	g := newNestedGrammar()
+	if _, ok := sca.Use(g).(LimitError); !ok {
+		t.Fatalf("expected a LimitError")
+	}
+	if err := sca.Use(Lit("[[[]]]")); err != nil {
+		t.Errorf("the next Use should start with new counters: %v", err)
+	}
+	if err := sca.Use(g); err != nil {
+		t.Errorf("the next Use should start with new counters: %v", err)
+	}
+	if !sca.AtEnd() {
+		t.Errorf("unexpected tail: %q", sca.Tail())
+	}
+}
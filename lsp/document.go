@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aiq/tok"
+)
+
+// Document is an open text document that gets parsed with the Grammar of its language.
+type Document struct {
+	URI        string
+	LanguageID string
+	inc        *tok.Incremental
+	sca        *tok.Scanner
+	err        error
+}
+
+func newDocument(uri string, lang string, g tok.Grammar, text string) *Document {
+	d := &Document{
+		URI:        uri,
+		LanguageID: lang,
+		inc:        tok.NewIncremental(lang, g),
+	}
+	d.set(d.inc.Parse(text))
+	return d
+}
+
+func (d *Document) set(g *tok.Graph, err error) {
+	d.sca = tok.NewScanner(d.inc.Text())
+	d.err = err
+	if err == nil && int(g.Root.To()) < len(d.inc.Text()) {
+		d.err = d.sca.ErrorAt(g.Root.To(), "end of text")
+	}
+}
+
+// Text returns the current text of d.
+func (d *Document) Text() string {
+	return d.inc.Text()
+}
+
+// Graph returns the Graph of the last parse, the value is nil if the parse failed.
+func (d *Document) Graph() *tok.Graph {
+	return d.inc.Graph()
+}
+
+// Err returns the error of the last parse.
+func (d *Document) Err() error {
+	return d.err
+}
+
+// Edit replaces the text in r with text, the whole text will be replaced if r is nil.
+func (d *Document) Edit(r *Range, text string) error {
+	if r == nil {
+		d.set(d.inc.Parse(text))
+		return nil
+	}
+	a, ok := d.marker(r.Start)
+	if !ok {
+		return fmt.Errorf("invalid position %d:%d", r.Start.Line, r.Start.Character)
+	}
+	b, ok := d.marker(r.End)
+	if !ok || b < a {
+		return fmt.Errorf("invalid position %d:%d", r.End.Line, r.End.Character)
+	}
+	g, _, err := d.inc.Edit(tok.MakeEdit(a, b, text))
+	d.set(g, err)
+	return nil
+}
+
+func (d *Document) marker(p Position) (tok.Marker, bool) {
+	return d.sca.MarkerAt(tok.Position{Line: p.Line + 1, Column: p.Character + 1}, tok.UTF16)
+}
+
+func (d *Document) position(m tok.Marker) Position {
+	p := d.sca.Position(m, tok.UTF16)
+	return Position{Line: p.Line - 1, Character: p.Column - 1}
+}
+
+func (d *Document) span(t tok.Token) Range {
+	return Range{d.position(t.From()), d.position(t.To())}
+}
+
+// Diagnostics returns the Diagnostics of the last parse.
+func (d *Document) Diagnostics() []Diagnostic {
+	if d.err == nil {
+		return []Diagnostic{}
+	}
+	m := tok.Marker(0)
+	var readErr tok.ReadError
+	var limitErr tok.LimitError
+	if errors.As(d.err, &readErr) {
+		m = readErr.Marker
+	} else if errors.As(d.err, &limitErr) {
+		m = limitErr.Marker
+	}
+	p := d.position(m)
+	return []Diagnostic{{
+		Range:    Range{p, p},
+		Severity: severityError,
+		Source:   d.LanguageID,
+		Message:  d.err.Error(),
+	}}
+}
+
+// SemanticTokens encodes the picked Segments of the Graph as semantic tokens, types maps a Rule name to its token type.
+// Inner Nodes win, the parts of a Node that no inner Node covers get the type of the Node.
+// Tokens that cover multiple lines will be split into one token per line.
+func (d *Document) SemanticTokens(types map[string]int) SemanticTokens {
+	res := SemanticTokens{Data: []int{}}
+	g := d.Graph()
+	if g == nil || len(g.Root.Nodes) == 0 {
+		return res
+	}
+	spans := []typedToken{}
+	appendTyped(&spans, g.Root, types, -1)
+	prev := Position{}
+	for _, span := range spans {
+		a, b := d.position(span.From()), d.position(span.To())
+		for line := a.Line; line <= b.Line; line++ {
+			start, end := 0, b.Character
+			if line == a.Line {
+				start = a.Character
+			}
+			if line < b.Line {
+				end = d.lineLen(line)
+			}
+			if end <= start {
+				continue
+			}
+			delta := start
+			if line == prev.Line {
+				delta -= prev.Character
+			}
+			res.Data = append(res.Data, line-prev.Line, delta, end-start, span.typ, 0)
+			prev = Position{line, start}
+		}
+	}
+	return res
+}
+
+type typedToken struct {
+	tok.Token
+	typ int
+}
+
+// appendTyped appends the parts of n with a token type in text order, typ is the type of the parent Node.
+func appendTyped(spans *[]typedToken, n *tok.Node, types map[string]int, typ int) {
+	if t, ok := types[n.Info]; ok {
+		typ = t
+	}
+	add := func(a, b tok.Marker) {
+		if typ >= 0 && a < b {
+			*spans = append(*spans, typedToken{tok.MakeToken(a, b), typ})
+		}
+	}
+	at := n.From()
+	for _, sub := range n.Nodes {
+		add(at, sub.From())
+		appendTyped(spans, sub, types, typ)
+		at = sub.To()
+	}
+	add(at, n.To())
+}
+
+// lineLen returns the length of line in UTF-16 code units without the line break.
+func (d *Document) lineLen(line int) int {
+	m, ok := d.marker(Position{Line: line + 1})
+	if !ok {
+		return d.position(tok.Marker(len(d.Text()))).Character
+	}
+	return d.position(m - 1).Character
+}
+
+// Symbols returns a DocumentSymbol for each Node of the Graph that has sub Nodes.
+func (d *Document) Symbols() []DocumentSymbol {
+	g := d.Graph()
+	if g == nil {
+		return []DocumentSymbol{}
+	}
+	return d.appendSymbols([]DocumentSymbol{}, g.Root.Nodes)
+}
+
+func (d *Document) appendSymbols(symbols []DocumentSymbol, nodes []*tok.Node) []DocumentSymbol {
+	for _, n := range nodes {
+		if len(n.Nodes) == 0 {
+			continue
+		}
+		r := d.span(n.Token)
+		symbols = append(symbols, DocumentSymbol{
+			Name:           n.Info,
+			Kind:           symbolKindObj,
+			Range:          r,
+			SelectionRange: Range{r.Start, r.Start},
+			Children:       d.appendSymbols(nil, n.Nodes),
+		})
+	}
+	return symbols
+}
+
+// FoldingRanges returns a FoldingRange for each Node of the Graph that covers multiple lines.
+// Only the outermost Node that starts at a line will be used.
+func (d *Document) FoldingRanges() []FoldingRange {
+	res := []FoldingRange{}
+	g := d.Graph()
+	if g == nil {
+		return res
+	}
+	used := map[int]bool{}
+	var walk func(nodes []*tok.Node)
+	walk = func(nodes []*tok.Node) {
+		for _, n := range nodes {
+			r := d.span(n.Token)
+			if r.End.Line > r.Start.Line && !used[r.Start.Line] {
+				used[r.Start.Line] = true
+				res = append(res, FoldingRange{r.Start.Line, r.End.Line})
+			}
+			walk(n.Nodes)
+		}
+	}
+	walk(g.Root.Nodes)
+	return res
+}
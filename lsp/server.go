@@ -0,0 +1,237 @@
+// Package lsp serves tok Grammars over the Language Server Protocol.
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aiq/tok"
+)
+
+type language struct {
+	id      string
+	exts    []string
+	grammar func() tok.Grammar
+}
+
+// Server is a language server that parses the documents with the Grammar of the registered language.
+type Server struct {
+	langs []*language
+	docs  map[string]*Document
+	types []string
+	conn  *conn
+}
+
+// NewServer creates a Server without languages.
+func NewServer() *Server {
+	return &Server{
+		docs: map[string]*Document{},
+	}
+}
+
+// Register registers the Grammar that f creates for the language id and the file extensions exts.
+// The Server calls f for each opened document.
+// The names of the Rules become the semantic token types.
+// A language that is registered again keeps its place, the first registered language wins if several share an extension.
+func (srv *Server) Register(id string, exts []string, f func() tok.Grammar) {
+	srv.langs = append(srv.langs, &language{id, exts, f})
+	for i, l := range srv.langs[:len(srv.langs)-1] {
+		if l.id == id {
+			srv.langs[i] = srv.langs[len(srv.langs)-1]
+			srv.langs = srv.langs[:len(srv.langs)-1]
+			break
+		}
+	}
+	known := map[string]bool{}
+	for _, t := range srv.types {
+		known[t] = true
+	}
+	for _, r := range f().Grammar() {
+		if !known[r.Name] {
+			known[r.Name] = true
+			srv.types = append(srv.types, r.Name)
+		}
+	}
+	sort.Strings(srv.types)
+}
+
+// TokenTypes returns the semantic token types of the Server.
+func (srv *Server) TokenTypes() []string {
+	return srv.types
+}
+
+// Document returns the open document with uri.
+func (srv *Server) Document(uri string) (*Document, bool) {
+	d, ok := srv.docs[uri]
+	return d, ok
+}
+
+// language returns the language with id or the first registered language that has the file extension of uri.
+func (srv *Server) language(id string, uri string) (*language, bool) {
+	for _, l := range srv.langs {
+		if l.id == id {
+			return l, true
+		}
+	}
+	for _, l := range srv.langs {
+		for _, ext := range l.exts {
+			if len(uri) > len(ext) && uri[len(uri)-len(ext):] == ext {
+				return l, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Serve reads requests from r and writes the responses to w until the client sends exit or r ends.
+func (srv *Server) Serve(r io.Reader, w io.Writer) error {
+	srv.conn = newConn(r, w)
+	for {
+		msg, err := srv.conn.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := srv.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (srv *Server) handle(msg *message) error {
+	result, err := srv.dispatch(msg)
+	if msg.ID == nil {
+		if err == nil || err == errMethodNotFound {
+			return nil
+		}
+		return srv.logError(msg.Method + ": " + err.Error())
+	}
+	if err == errMethodNotFound {
+		return srv.conn.replyError(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+	} else if err != nil {
+		return srv.conn.replyError(msg.ID, codeInvalidParams, err.Error())
+	}
+	return srv.conn.reply(msg.ID, result)
+}
+
+var errMethodNotFound = fmt.Errorf("method not found")
+
+func (srv *Server) dispatch(msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return srv.initialize(), nil
+	case "initialized", "shutdown", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "textDocument/didOpen":
+		p := didOpenParams{}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return nil, srv.didOpen(p)
+	case "textDocument/didChange":
+		p := didChangeParams{}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return nil, srv.didChange(p)
+	case "textDocument/didClose":
+		p := documentParams{}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(srv.docs, p.TextDocument.URI)
+		return nil, srv.publish(p.TextDocument.URI, []Diagnostic{})
+	case "textDocument/semanticTokens/full":
+		d, err := srv.document(msg.Params)
+		if err != nil {
+			return nil, err
+		}
+		types := map[string]int{}
+		for i, t := range srv.types {
+			types[t] = i
+		}
+		return d.SemanticTokens(types), nil
+	case "textDocument/documentSymbol":
+		d, err := srv.document(msg.Params)
+		if err != nil {
+			return nil, err
+		}
+		return d.Symbols(), nil
+	case "textDocument/foldingRange":
+		d, err := srv.document(msg.Params)
+		if err != nil {
+			return nil, err
+		}
+		return d.FoldingRanges(), nil
+	}
+	return nil, errMethodNotFound
+}
+
+func (srv *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":       syncIncremental,
+			"documentSymbolProvider": true,
+			"foldingRangeProvider":   true,
+			"semanticTokensProvider": map[string]interface{}{
+				"legend": map[string]interface{}{
+					"tokenTypes":     srv.types,
+					"tokenModifiers": []string{},
+				},
+				"full": true,
+			},
+		},
+		"serverInfo": map[string]string{"name": "tok-lsp"},
+	}
+}
+
+func (srv *Server) document(params json.RawMessage) (*Document, error) {
+	p := documentParams{}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	d, ok := srv.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", p.TextDocument.URI)
+	}
+	return d, nil
+}
+
+func (srv *Server) didOpen(p didOpenParams) error {
+	item := p.TextDocument
+	l, ok := srv.language(item.LanguageID, item.URI)
+	if !ok {
+		return fmt.Errorf("no grammar for %s", item.URI)
+	}
+	d := newDocument(item.URI, l.id, l.grammar(), item.Text)
+	srv.docs[item.URI] = d
+	return srv.publish(d.URI, d.Diagnostics())
+}
+
+func (srv *Server) didChange(p didChangeParams) error {
+	d, ok := srv.docs[p.TextDocument.URI]
+	if !ok {
+		return fmt.Errorf("unknown document %s", p.TextDocument.URI)
+	}
+	for _, c := range p.ContentChanges {
+		if err := d.Edit(c.Range, c.Text); err != nil {
+			return err
+		}
+	}
+	return srv.publish(d.URI, d.Diagnostics())
+}
+
+// logError sends text as error to the client, notifications can't reply with an error.
+func (srv *Server) logError(text string) error {
+	return srv.conn.notify("window/logMessage", logMessageParams{messageError, text})
+}
+
+func (srv *Server) publish(uri string, diags []Diagnostic) error {
+	return srv.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{uri, diags})
+}
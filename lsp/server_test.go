@@ -0,0 +1,148 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+)
+
+func writeRequest(b *bytes.Buffer, id int, method string, params interface{}) {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(b, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func readResponses(t *testing.T, b *bytes.Buffer) []message {
+	res := []message{}
+	c := newConn(b, nil)
+	for {
+		msg, err := c.read()
+		if err == io.EOF {
+			return res
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res = append(res, *msg)
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	srv.Register("json", []string{".json"}, func() tok.Grammar { return grammar.JSON() })
+
+	uri := "file:///a.json"
+	text := "{\n  \"a\": [1,\n    2]\n}"
+	in := &bytes.Buffer{}
+	writeRequest(in, 1, "initialize", map[string]interface{}{})
+	writeRequest(in, 0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": textDocumentItem{URI: uri, Text: text},
+	})
+	writeRequest(in, 2, "textDocument/foldingRange", documentParams{textDocumentIdentifier{uri}})
+	writeRequest(in, 0, "textDocument/didChange", didChangeParams{
+		TextDocument: textDocumentIdentifier{uri},
+		ContentChanges: []contentChange{
+			{Range: &Range{Position{2, 4}, Position{2, 5}}, Text: "x"},
+		},
+	})
+	writeRequest(in, 3, "unknown/method", nil)
+	writeRequest(in, 0, "textDocument/didChange", didChangeParams{
+		TextDocument: textDocumentIdentifier{"file:///b.json"},
+	})
+	writeRequest(in, 0, "exit", nil)
+
+	out := &bytes.Buffer{}
+	if err := srv.Serve(in, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msgs := readResponses(t, out)
+	if len(msgs) != 6 {
+		t.Fatalf("expected 6 messages, got %d", len(msgs))
+	}
+
+	raw, _ := json.Marshal(msgs[0].Result)
+	if !strings.Contains(string(raw), `"tokenTypes":["array",`) {
+		t.Errorf("unexpected initialize result: %s", raw)
+	}
+
+	raw, _ = json.Marshal(msgs[1].Params)
+	if !strings.Contains(string(raw), `"diagnostics":[]`) {
+		t.Errorf("unexpected diagnostics after open: %s", raw)
+	}
+
+	raw, _ = json.Marshal(msgs[2].Result)
+	folds := []FoldingRange{}
+	json.Unmarshal(raw, &folds)
+	if fmt.Sprint(folds) != "[{0 3} {1 3} {2 3}]" {
+		t.Errorf("unexpected folding ranges: %v", folds)
+	}
+
+	diags := publishDiagnosticsParams{}
+	json.Unmarshal(msgs[3].Params, &diags)
+	if len(diags.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic after the change: %v", diags)
+	}
+	if diags.Diagnostics[0].Range.Start != (Position{1, 2}) {
+		t.Errorf("unexpected diagnostic: %v", diags.Diagnostics[0])
+	}
+
+	if msgs[4].Error == nil || msgs[4].Error.Code != codeMethodNotFound {
+		t.Errorf("expected a method not found error: %v", msgs[4])
+	}
+
+	log := logMessageParams{}
+	json.Unmarshal(msgs[5].Params, &log)
+	if msgs[5].Method != "window/logMessage" || log.Message != "textDocument/didChange: unknown document file:///b.json" {
+		t.Errorf("unexpected log message: %v %v", msgs[5].Method, log)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	text := "[\"a\",\n\"bc\"]"
+	d := newDocument("x.json", "json", grammar.JSON(), text)
+	if d.Err() != nil {
+		t.Fatalf("unexpected error: %v", d.Err())
+	}
+	types := map[string]int{"characters": 1, "character": 2}
+	st := d.SemanticTokens(types)
+	exp := []int{0, 2, 1, 2, 0, 1, 1, 1, 2, 0, 0, 1, 1, 2, 0}
+	if fmt.Sprint(st.Data) != fmt.Sprint(exp) {
+		t.Errorf("expected %v, got %v", exp, st.Data)
+	}
+	st = d.SemanticTokens(map[string]int{"string": 3, "character": 2})
+	exp = []int{0, 1, 1, 3, 0, 0, 1, 1, 2, 0, 0, 1, 1, 3, 0, 1, 0, 1, 3, 0, 0, 1, 1, 2, 0, 0, 1, 1, 2, 0, 0, 1, 1, 3, 0}
+	if fmt.Sprint(st.Data) != fmt.Sprint(exp) {
+		t.Errorf("expected %v, got %v", exp, st.Data)
+	}
+	symbols := d.Symbols()
+	if len(symbols) != 1 || symbols[0].Name != "element" {
+		t.Errorf("unexpected symbols: %v", symbols)
+	}
+}
+
+func TestServerLanguageByExtension(t *testing.T) {
+	srv := NewServer()
+	srv.Register("json", []string{".json", ".conf"}, func() tok.Grammar { return grammar.JSON() })
+	srv.Register("lua", []string{".lua", ".conf"}, func() tok.Grammar { return grammar.Lua() })
+	srv.Register("json", []string{".json", ".conf"}, func() tok.Grammar { return grammar.JSONC() })
+	for i := 0; i < 20; i++ {
+		l, ok := srv.language("", "file:///a.conf")
+		if !ok || l.id != "json" {
+			t.Fatalf("expected the first registered language for .conf: %v", l)
+		}
+	}
+	if l, ok := srv.language("", "file:///a.lua"); !ok || l.id != "lua" {
+		t.Errorf("unexpected language for .lua: %v", l)
+	}
+	if len(srv.langs) != 2 || srv.langs[0].grammar().What() != "jsonc" {
+		t.Errorf("the registered language should be replaced in place: %v", srv.langs)
+	}
+}
@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type LogEntry struct {
-	EnterAt int
-	Info    string
-	Level   int
-	ExitAt  int
-	Error   error
+	EnterAt   int
+	Info      string
+	Level     int
+	ExitAt    int
+	Error     error
+	EnterTime time.Time
+	ExitTime  time.Time
+	index     int
 }
 
 func (e LogEntry) String() string {
@@ -21,6 +25,14 @@ func (e LogEntry) String() string {
 type Log struct {
 	Entries []LogEntry
 	level   int
+	now     func() time.Time
+}
+
+func (l *Log) time() time.Time {
+	if l.now == nil {
+		return time.Now()
+	}
+	return l.now()
 }
 
 func MonitorGrammar(g Grammar) *Log {
@@ -33,16 +45,21 @@ func (l *Log) Enter(info string, pos int) *LogEntry {
 	l.level++
 	i := len(l.Entries)
 	l.Entries = append(l.Entries, LogEntry{
-		EnterAt: pos,
-		Info:    info,
-		Level:   l.level,
+		EnterAt:   pos,
+		Info:      info,
+		Level:     l.level,
+		EnterTime: l.time(),
+		index:     i,
 	})
 	return &l.Entries[i]
 }
 
+// Exit sets the exit values of e, e can be a stale pointer if Entries grew since Enter.
 func (l *Log) Exit(e *LogEntry, pos int, err error) {
+	e = &l.Entries[e.index]
 	e.ExitAt = pos
 	e.Error = err
+	e.ExitTime = l.time()
 	l.level--
 }
 
@@ -0,0 +1,18 @@
+package tok
+
+import "testing"
+
+func TestLogExitAfterGrowth(t *testing.T) {
+	l := &Log{}
+	outer := l.Enter("outer", 0)
+	for i := 0; i < 100; i++ {
+		l.Exit(l.Enter("inner", i), i+1, nil)
+	}
+	l.Exit(outer, 100, nil)
+	if e := l.Entries[0]; e.Info != "outer" || e.ExitAt != 100 {
+		t.Errorf("the exit of a stale entry got lost: %v", e)
+	}
+	if e := l.Entries[50]; e.ExitAt != 50 {
+		t.Errorf("unexpected entry: %v", e)
+	}
+}
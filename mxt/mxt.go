@@ -0,0 +1,312 @@
+// Package mxt decodes and encodes MXT files, a format that stores named text chunks in one file.
+// The format is described on https://mxt.aiq.dk/
+package mxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+)
+
+// Chunk is a named text in a MXT file.
+// The Salt of a Chunk prefixes the marker of the next Chunk, the Content can therefore have lines that start with //
+// if they don't start with //Salt.
+type Chunk struct {
+	Name    string
+	Comment string
+	Content string
+	Salt    string
+}
+
+// ------------------------------------------------------------------------------
+// Decoder reads the Chunks of a MXT file one by one from a stream.
+// The line break before the next header is \r\n if the header line of the Chunk ends with \r\n, otherwise \n.
+// A Content that ends with \r keeps therefore its \r in a file that the Encoder wrote.
+type Decoder struct {
+	r      *bufio.Reader
+	g      *grammar.MXTReader
+	line   int
+	done   bool
+	unread *string
+	salt   string
+}
+
+// NewDecoder creates a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: bufio.NewReader(r),
+		g: grammar.MXT(),
+	}
+}
+
+// readLine returns the next line without the line break, ok is false after the last line.
+func (d *Decoder) readLine() (line string, ok bool, err error) {
+	if d.unread != nil {
+		line, d.unread = *d.unread, nil
+		d.line++
+		return line, true, nil
+	}
+	if d.done {
+		return "", false, nil
+	}
+	line, err = d.r.ReadString('\n')
+	if err == io.EOF {
+		d.done = true
+	} else if err != nil {
+		return "", false, err
+	}
+	d.line++
+	return strings.TrimSuffix(line, "\n"), true, nil
+}
+
+func (d *Decoder) unreadLine(line string) {
+	d.unread = &line
+	d.line--
+}
+
+// Decode returns the next Chunk, io.EOF at the end of the stream.
+func (d *Decoder) Decode() (Chunk, error) {
+	first, ok, err := d.readLine()
+	if err != nil {
+		return Chunk{}, err
+	}
+	if !ok || (d.line == 1 && d.done && first == "") {
+		return Chunk{}, io.EOF
+	}
+	if !strings.HasPrefix(first, "//"+d.salt) {
+		return Chunk{}, fmt.Errorf("mxt: line %d: expected a chunk header", d.line)
+	}
+	crlf := strings.HasSuffix(first, "\r")
+	c, err := d.header(first)
+	if err != nil {
+		return Chunk{}, err
+	}
+	lines := []string{}
+	for {
+		line, ok, err := d.readLine()
+		if err != nil {
+			return Chunk{}, err
+		}
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(line, "//"+c.Salt) {
+			d.unreadLine(line)
+			if n := len(lines); n > 0 && crlf {
+				lines[n-1] = strings.TrimSuffix(lines[n-1], "\r")
+			}
+			break
+		}
+		lines = append(lines, line)
+	}
+	c.Content = strings.Join(lines, "\n")
+	d.salt = c.Salt
+	return c, nil
+}
+
+// header reads the lines of a header until the header rule of the MXT grammar matches.
+func (d *Decoder) header(first string) (Chunk, error) {
+	text := strings.TrimSuffix(first, "\r")
+	start := d.line
+	for {
+		if c, ok := d.parseHeader(text); ok {
+			return c, nil
+		}
+		line, ok, err := d.readLine()
+		if err != nil {
+			return Chunk{}, err
+		}
+		if !ok || !strings.HasPrefix(line, "//") {
+			return Chunk{}, fmt.Errorf("mxt: line %d: invalid chunk header", start)
+		}
+		text += "\n" + strings.TrimSuffix(line, "\r")
+	}
+}
+
+func (d *Decoder) parseHeader(text string) (Chunk, bool) {
+	sca := tok.NewScanner(text)
+	basket := sca.NewBasket()
+	basket.PickWith(&d.g.Name, &d.g.Comment, &d.g.Salt)
+	if sca.Use(&d.g.Header) != nil || !sca.AtEnd() {
+		return Chunk{}, false
+	}
+	c := Chunk{}
+	for _, seg := range basket.Picked() {
+		switch seg.Info {
+		case "name":
+			c.Name = sca.Get(seg.Token)
+		case "comment":
+			c.Comment = decodeComment(sca.Get(seg.Token))
+		case "salt":
+			c.Salt = strings.TrimSpace(sca.Get(seg.Token))
+		}
+	}
+	return c, true
+}
+
+// decodeComment joins the comment lines with a space, an empty comment line is a line break.
+func decodeComment(raw string) string {
+	lines := strings.Split(raw, "\n")
+	b := &strings.Builder{}
+	text := false
+	for i, line := range lines {
+		if i > 0 {
+			if !strings.HasPrefix(line, "//") {
+				continue
+			}
+			line = strings.TrimPrefix(line, "//")
+			if strings.TrimSpace(line) == "" {
+				b.WriteString("\n")
+				text = false
+				continue
+			}
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if text {
+			b.WriteString(" ")
+		}
+		b.WriteString(line)
+		text = true
+	}
+	return b.String()
+}
+
+// Decode reads all Chunks of a MXT text.
+func Decode(text string) ([]Chunk, error) {
+	d := NewDecoder(strings.NewReader(text))
+	chunks := []Chunk{}
+	for {
+		c, err := d.Decode()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+// ------------------------------------------------------------------------------
+// Encoder writes Chunks one by one as MXT file to a stream.
+type Encoder struct {
+	w     io.Writer
+	g     *grammar.MXTReader
+	count int
+	salt  string
+}
+
+// NewEncoder creates an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+		g: grammar.MXT(),
+	}
+}
+
+// Encode writes c to the stream.
+// Encode keeps the Salt of c if the Content has no line that starts with //Salt, otherwise it picks a new Salt.
+// Returns an error if the Name is not a word or if the Comment has spaces around the lines or an arrow.
+func (e *Encoder) Encode(c Chunk) error {
+	if !isWord(c.Salt) || hasMarker(c.Content, c.Salt) {
+		c.Salt = pickSalt(c.Content)
+	}
+	header := e.header(c)
+	d := &Decoder{g: e.g}
+	dc, ok := d.parseHeader(header)
+	if !ok || dc.Name != c.Name || dc.Comment != c.Comment || dc.Salt != c.Salt {
+		return fmt.Errorf("mxt: can't encode the header of chunk %q with comment %q", c.Name, c.Comment)
+	}
+	b := &strings.Builder{}
+	if e.count > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(header)
+	if c.Content != "" {
+		b.WriteString("\n")
+		b.WriteString(c.Content)
+	}
+	if _, err := io.WriteString(e.w, b.String()); err != nil {
+		return err
+	}
+	e.count++
+	e.salt = c.Salt
+	return nil
+}
+
+// header creates the header of c, a multi line comment gets a line per comment line.
+func (e *Encoder) header(c Chunk) string {
+	b := &strings.Builder{}
+	b.WriteString("//" + e.salt + " " + c.Name)
+	if !strings.Contains(c.Comment, "\n") {
+		if c.Comment != "" {
+			b.WriteString(" " + c.Comment)
+		}
+		b.WriteString(" -->")
+	} else {
+		for i, line := range strings.Split(c.Comment, "\n") {
+			if i > 0 {
+				b.WriteString("\n//")
+			}
+			if line != "" {
+				b.WriteString("\n// " + line)
+			}
+		}
+		b.WriteString("\n//-->")
+	}
+	if c.Salt != "" {
+		b.WriteString(" " + c.Salt)
+	}
+	return b.String()
+}
+
+// isWord returns true if str has no space or control character.
+func isWord(str string) bool {
+	return strings.IndexFunc(str, func(r rune) bool { return r <= ' ' }) < 0
+}
+
+// hasMarker returns true if content has a line that starts with //salt.
+func hasMarker(content string, salt string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "//"+salt) {
+			return true
+		}
+	}
+	return false
+}
+
+// pickSalt returns an empty salt if content has no line that starts with //, otherwise the first of
+// A to Z, AA to ZZ and so on that no line of content uses.
+func pickSalt(content string) string {
+	if !hasMarker(content, "") {
+		return ""
+	}
+	for n := 1; ; n++ {
+		for c := 'A'; c <= 'Z'; c++ {
+			salt := strings.Repeat(string(c), n)
+			if !hasMarker(content, salt) {
+				return salt
+			}
+		}
+	}
+}
+
+// Encode creates the MXT text of the Chunks.
+// Decode returns for the text the Chunks with the Salts that Encode used.
+func Encode(chunks []Chunk) (string, error) {
+	b := &strings.Builder{}
+	e := NewEncoder(b)
+	for _, c := range chunks {
+		if err := e.Encode(c); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,171 @@
+package mxt
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+)
+
+const example = `//---------------------------------------------------------------- user.json -->
+{
+    "user": "alucard"
+}
+//--------------------------------------------------------------- connection.ini
+// comment line that is not part of the ini file,
+// comment lines will be joined with a space character
+//
+// empty comment lines will generate a newline character in the comment
+//----------------------------------------------------------------------------->
+request: GET
+// user.pgp --> XYZ
+-----BEGIN PGP MESSAGE-----
+// not a marker
+-----END PGP MESSAGE-----
+//XYZ hello-world.h -->
+//---------------------------------------------------------- hello-world.c --> X
+// this is part of hello-world.c
+int main(void) {
+return 0;
+}`
+
+func TestDecode(t *testing.T) {
+	chunks, err := Decode(example)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := []Chunk{
+		{Name: "user.json", Content: "{\n    \"user\": \"alucard\"\n}"},
+		{
+			Name:    "connection.ini",
+			Comment: "comment line that is not part of the ini file, comment lines will be joined with a space character\nempty comment lines will generate a newline character in the comment",
+			Content: "request: GET",
+		},
+		{Name: "user.pgp", Content: "-----BEGIN PGP MESSAGE-----\n// not a marker\n-----END PGP MESSAGE-----", Salt: "XYZ"},
+		{Name: "hello-world.h"},
+		{Name: "hello-world.c", Content: "// this is part of hello-world.c\nint main(void) {\nreturn 0;\n}", Salt: "X"},
+	}
+	if !reflect.DeepEqual(chunks, exp) {
+		t.Errorf("unexpected chunks:\n%q\nexpected:\n%q", chunks, exp)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	cases := []string{
+		"no header",
+		"// name\nwithout arrow",
+		"// a --> X\n// b -->",
+	}
+	for i, c := range cases {
+		chunks, err := Decode(c)
+		if i == 2 {
+			if err != nil || len(chunks) != 1 || chunks[0].Content != "// b -->" {
+				t.Errorf("%d expected one chunk: %q %v", i, chunks, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%d expected an error", i)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cases := [][]Chunk{
+		{},
+		{{Name: "a"}},
+		{{Name: "a", Content: "\n"}, {Name: "b", Content: "x\n\ny\n"}},
+		{{Name: "a.c", Comment: "the main file", Content: "// comment\nint main();"}, {Name: "b", Content: "//A\n//B"}},
+		{{Name: "x", Comment: "one\ntwo\n\nthree", Content: "text"}, {Name: "y", Content: "//"}},
+		{{Name: "s", Content: "content", Salt: "KEEP"}},
+		{{Name: "crlf", Content: "a\r\nb\r\n"}, {Name: "next"}},
+		{{Name: "cr", Content: "a\r"}, {Name: "next", Content: "\r"}, {Name: "last"}},
+	}
+	for i, c := range cases {
+		text, err := Encode(c)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if len(c) > 0 {
+			sca := tok.NewScanner(text)
+			if err := sca.Use(grammar.MXT()); err != nil || !sca.AtEnd() {
+				t.Errorf("%d the MXT grammar can't read the text: %v\n%s", i, err, text)
+			}
+		}
+		chunks, err := Decode(text)
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v\n%s", i, err, text)
+		}
+		if len(chunks) != len(c) {
+			t.Fatalf("%d expected %d chunks, got %d:\n%s", i, len(c), len(chunks), text)
+		}
+		for j := range c {
+			got := chunks[j]
+			got.Salt = c[j].Salt
+			if got != c[j] {
+				t.Errorf("%d.%d unexpected chunk %q, expected %q:\n%s", i, j, got, c[j], text)
+			}
+			if hasMarker(c[j].Content, chunks[j].Salt) {
+				t.Errorf("%d.%d the content uses the salt %q", i, j, chunks[j].Salt)
+			}
+		}
+	}
+	text, _ := Encode([]Chunk{{Name: "b", Content: "//A\n//B"}})
+	if text != "// b --> C\n//A\n//B" {
+		t.Errorf("unexpected salt: %q", text)
+	}
+}
+
+func TestDecodeCRLF(t *testing.T) {
+	chunks, err := Decode("// a -->\r\nx\r\ny\r\n// b -->\r\nz\r")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := []Chunk{{Name: "a", Content: "x\r\ny"}, {Name: "b", Content: "z\r"}}
+	if len(chunks) != len(exp) || chunks[0] != exp[0] || chunks[1] != exp[1] {
+		t.Errorf("unexpected chunks: %q", chunks)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	cases := []Chunk{
+		{Name: ""},
+		{Name: "two words"},
+		{Name: "a", Comment: " space"},
+		{Name: "a", Comment: "an arrow--> inside"},
+	}
+	for i, c := range cases {
+		if _, err := Encode([]Chunk{c}); err == nil {
+			t.Errorf("%d expected an error", i)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewEncoder(b)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := e.Encode(Chunk{Name: name, Content: "//" + name}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	d := NewDecoder(strings.NewReader(b.String()))
+	names := []string{}
+	for {
+		c, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, c.Name+"="+c.Content)
+	}
+	if str := strings.Join(names, " "); str != "a=//a b=//b c=//c" {
+		t.Errorf("unexpected chunks: %s", str)
+	}
+}
@@ -0,0 +1,145 @@
+package tok
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Unit defines in which unit a column will be counted.
+type Unit int
+
+const (
+	// Bytes counts the column in bytes.
+	Bytes Unit = iota
+	// Runes counts the column in runes.
+	Runes
+	// UTF16 counts the column in UTF-16 code units, like the Language Server Protocol.
+	UTF16
+)
+
+// Position represents a Marker as line and column value.
+// Offset is the byte offset in File.
+type Position struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid reports if p is a valid position.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns a readable representation of a Position, like "file:12:7".
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+//------------------------------------------------------------------------------
+
+// lineStarts returns the byte offsets where the lines in the full string start.
+// The index will be created once and reused afterwards.
+func (s *Scanner) lineStarts() []int {
+	if s.lines == nil {
+		s.lines = []int{0}
+		for i := 0; i < len(s.full); i++ {
+			if s.full[i] == '\n' {
+				s.lines = append(s.lines, i+1)
+			}
+		}
+	}
+	return s.lines
+}
+
+// lineOf returns the zero based line index of the byte offset.
+func (s *Scanner) lineOf(offset int) int {
+	lines := s.lineStarts()
+	return sort.Search(len(lines), func(i int) bool { return lines[i] > offset }) - 1
+}
+
+func countUnits(str string, u Unit) int {
+	switch u {
+	case Runes:
+		return utf8.RuneCountInString(str)
+	case UTF16:
+		n := 0
+		for _, r := range str {
+			if r >= 0x10000 {
+				n += 2
+			} else {
+				n++
+			}
+		}
+		return n
+	}
+	return len(str)
+}
+
+// Position returns the Position of m with a column in the unit u.
+// If s reads from a Source refers the Position to the File that contains m.
+func (s *Scanner) Position(m Marker, u Unit) Position {
+	if s.src != nil {
+		return s.src.Position(m, u)
+	}
+	offset := int(m)
+	if offset < 0 || offset > len(s.full) {
+		return Position{}
+	}
+	line := s.lineOf(offset)
+	start := s.lineStarts()[line]
+	return Position{
+		Offset: offset,
+		Line:   line + 1,
+		Column: countUnits(s.full[start:offset], u) + 1,
+	}
+}
+
+// Positions returns the Positions where t starts and ends with a column in the unit u.
+func (s *Scanner) Positions(t Token, u Unit) (Position, Position) {
+	return s.Position(t.from, u), s.Position(t.to, u)
+}
+
+// Pos returns the Position of the current position with a column in the unit u.
+func (s *Scanner) Pos(u Unit) Position {
+	return s.Position(s.Mark(), u)
+}
+
+// MarkerAt returns the Marker for a line and column in the unit u.
+// The Offset and File of p will be ignored.
+// Returns false if the line or column does not exist.
+func (s *Scanner) MarkerAt(p Position, u Unit) (Marker, bool) {
+	lines := s.lineStarts()
+	if p.Line < 1 || p.Line > len(lines) || p.Column < 1 {
+		return Marker(0), false
+	}
+	start := lines[p.Line-1]
+	end := len(s.full)
+	if p.Line < len(lines) {
+		end = lines[p.Line] - 1
+	}
+	col := 1
+	for i, r := range s.full[start:end] {
+		if col == p.Column {
+			return Marker(start + i), true
+		} else if col > p.Column {
+			return Marker(0), false
+		}
+		switch u {
+		case Runes:
+			col++
+		case UTF16:
+			col += countUnits(string(r), UTF16)
+		default:
+			col += utf8.RuneLen(r)
+		}
+	}
+	if col == p.Column {
+		return Marker(end), true
+	}
+	return Marker(0), false
+}
@@ -0,0 +1,49 @@
+package tok
+
+import "testing"
+
+func TestPosition(t *testing.T) {
+	inp := "ab\nä𝄞x\n\nend"
+	cases := []struct {
+		m    Marker
+		u    Unit
+		line int
+		col  int
+	}{
+		{0, Bytes, 1, 1},
+		{2, Bytes, 1, 3},
+		{3, Bytes, 2, 1},
+		{9, Bytes, 2, 7},
+		{9, Runes, 2, 3},
+		{9, UTF16, 2, 4},
+		{11, Runes, 3, 1},
+		{15, Runes, 4, 4},
+	}
+	sca := NewScanner(inp)
+	for i, c := range cases {
+		p := sca.Position(c.m, c.u)
+		if p.Line != c.line || p.Column != c.col || p.Offset != int(c.m) {
+			t.Errorf("%d unexpected position: %v != %d:%d", i, p, c.line, c.col)
+		}
+		m, ok := sca.MarkerAt(p, c.u)
+		if !ok || m != c.m {
+			t.Errorf("%d unexpected marker: %d != %d", i, m, c.m)
+		}
+	}
+
+	if _, ok := sca.MarkerAt(Position{Line: 1, Column: 4}, Bytes); ok {
+		t.Errorf("unexpected marker for column after the line end")
+	}
+	if _, ok := sca.MarkerAt(Position{Line: 5, Column: 1}, Bytes); ok {
+		t.Errorf("unexpected marker for invalid line")
+	}
+
+	from, to := sca.Positions(MakeToken(3, 11), Runes)
+	if from.String() != "2:1" || to.String() != "3:1" {
+		t.Errorf("unexpected positions: %v %v", from, to)
+	}
+	p := Position{File: "foo.lua", Line: 12, Column: 7}
+	if p.String() != "foo.lua:12:7" {
+		t.Errorf("unexpected string: %s", p.String())
+	}
+}
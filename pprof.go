@@ -0,0 +1,149 @@
+package tok
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// protoBuffer encodes the fields of a protocol buffer message.
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) uint(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(field) << 3)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int(field int, x int64) {
+	b.uint(field, uint64(x))
+}
+
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protoBuffer) packed(field int, values []uint64) {
+	sub := &protoBuffer{}
+	for _, v := range values {
+		sub.varint(v)
+	}
+	b.bytes(field, sub.data)
+}
+
+func (b *protoBuffer) message(field int, f func(sub *protoBuffer)) {
+	sub := &protoBuffer{}
+	f(sub)
+	b.bytes(field, sub.data)
+}
+
+// stringTable collects the strings of a pprof profile.
+type stringTable struct {
+	index   map[string]int64
+	strings []string
+}
+
+func (t *stringTable) id(str string) int64 {
+	if i, ok := t.index[str]; ok {
+		return i
+	}
+	i := int64(len(t.strings))
+	t.index[str] = i
+	t.strings = append(t.strings, str)
+	return i
+}
+
+// WritePprof writes the collected samples as gzip compressed pprof profile to w.
+// Each Rule is a function and the Rule stack the call stack of a sample.
+// The samples contain the calls, the self time in nanoseconds and the backtracked bytes.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	st := &stringTable{index: map[string]int64{}}
+	st.id("")
+	b := &protoBuffer{}
+
+	valueType := func(field int, typ, unit string) {
+		b.message(field, func(sub *protoBuffer) {
+			sub.int(1, st.id(typ))
+			sub.int(2, st.id(unit))
+		})
+	}
+	valueType(1, "calls", "count")
+	valueType(1, "time", "nanoseconds")
+	valueType(1, "backtracked", "bytes")
+
+	functions := map[string]uint64{}
+	names := []string{}
+	for _, sample := range p.samples.collect(nil) {
+		stack := sample.stack()
+		ids := []uint64{}
+		for i := len(stack) - 1; i >= 0; i-- {
+			name := stack[i]
+			id, ok := functions[name]
+			if !ok {
+				id = uint64(len(functions) + 1)
+				functions[name] = id
+				names = append(names, name)
+			}
+			ids = append(ids, id)
+		}
+		b.message(2, func(sub *protoBuffer) {
+			sub.packed(1, ids)
+			sub.packed(2, []uint64{
+				uint64(sample.calls),
+				uint64(sample.self.Nanoseconds()),
+				uint64(sample.backtracked),
+			})
+		})
+	}
+	for i := range names {
+		id := uint64(i + 1)
+		b.message(4, func(sub *protoBuffer) {
+			sub.uint(1, id)
+			sub.message(4, func(line *protoBuffer) {
+				line.uint(1, id)
+			})
+		})
+	}
+	for i, name := range names {
+		id := uint64(i + 1)
+		b.message(5, func(sub *protoBuffer) {
+			sub.uint(1, id)
+			sub.int(2, st.id(name))
+			sub.int(3, st.id(name))
+		})
+	}
+	timeNanos := p.start.UnixNano()
+	durationNanos := p.now().Sub(p.start).Nanoseconds()
+	typ, unit := st.id("time"), st.id("nanoseconds")
+	defaultType := st.id("time")
+	for _, str := range st.strings {
+		b.bytes(6, []byte(str))
+	}
+	b.int(9, timeNanos)
+	b.int(10, durationNanos)
+	b.message(11, func(sub *protoBuffer) {
+		sub.int(1, typ)
+		sub.int(2, unit)
+	})
+	b.int(12, 1)
+	b.int(14, defaultType)
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,251 @@
+package tok
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// RuleStats contains the aggregated statistics of a Rule.
+type RuleStats struct {
+	Name      string
+	Calls     int
+	Successes int
+	Failures  int
+	// Consumed is the number of bytes that the successful calls read.
+	Consumed int
+	// Backtracked is the number of bytes that were read and then given back.
+	Backtracked int
+	// Total is the time in the Rule including the sub Rules, Self without the sub Rules.
+	// Total counts for recursive Rules only the outermost call.
+	Total time.Duration
+	Self  time.Duration
+}
+
+type profileFrame struct {
+	stats    *RuleStats
+	sample   *profileSample
+	enterAt  Marker
+	furthest Marker
+	start    time.Time
+	sub      time.Duration
+}
+
+// profileSample contains the values of a Rule stack, the children are the samples of the stacks one Rule deeper.
+type profileSample struct {
+	name        string
+	parent      *profileSample
+	children    map[string]*profileSample
+	calls       int
+	self        time.Duration
+	backtracked int
+}
+
+// child returns the sample of the stack with the Rule name on top of s.
+func (s *profileSample) child(name string) *profileSample {
+	c, ok := s.children[name]
+	if !ok {
+		c = &profileSample{name: name, parent: s, children: map[string]*profileSample{}}
+		s.children[name] = c
+	}
+	return c
+}
+
+// stack returns the names of the Rules from the outermost Rule to the Rule of s.
+func (s *profileSample) stack() []string {
+	res := []string{}
+	for ; s.parent != nil; s = s.parent {
+		res = append([]string{s.name}, res...)
+	}
+	return res
+}
+
+// collect appends s and the samples below s with the children ordered by name.
+func (s *profileSample) collect(res []*profileSample) []*profileSample {
+	if s.parent != nil {
+		res = append(res, s)
+	}
+	names := []string{}
+	for name := range s.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res = s.children[name].collect(res)
+	}
+	return res
+}
+
+// Profiler is a Tracker that collects statistics about the Rules that read from a Scanner.
+// The Profiler must be the Tracker of the Scanner to record the backtracked bytes,
+// it forwards each update to Tracker if set.
+type Profiler struct {
+	Tracker Tracker
+	stats   map[string]*RuleStats
+	samples *profileSample
+	stack   []profileFrame
+	active  map[*RuleStats]int
+	start   time.Time
+	now     func() time.Time
+}
+
+// NewProfiler creates an empty Profiler.
+func NewProfiler() *Profiler {
+	p := &Profiler{now: time.Now}
+	p.Reset()
+	return p
+}
+
+// ProfileGrammar creates a Profiler that profiles all Rules of g.
+// Set the Profiler as Tracker of the Scanner to record the backtracked bytes.
+func ProfileGrammar(g Grammar) *Profiler {
+	p := NewProfiler()
+	p.Profile(g.Grammar()...)
+	return p
+}
+
+// Profile connects the Rules with p.
+func (p *Profiler) Profile(rules ...*Rule) {
+	for _, r := range rules {
+		r.Profile(p)
+	}
+}
+
+// Reset removes all collected statistics.
+func (p *Profiler) Reset() {
+	p.stats = map[string]*RuleStats{}
+	p.samples = &profileSample{children: map[string]*profileSample{}}
+	p.stack = []profileFrame{}
+	p.active = map[*RuleStats]int{}
+	p.start = p.now()
+}
+
+func (p *Profiler) Update(m Marker) {
+	if n := len(p.stack); n > 0 && m > p.stack[n-1].furthest {
+		p.stack[n-1].furthest = m
+	}
+	if p.Tracker != nil {
+		p.Tracker.Update(m)
+	}
+}
+
+// RevUpdate forwards m to the wrapped Tracker if it is a RevTracker.
+func (p *Profiler) RevUpdate(m Marker) {
+	if rt, ok := p.Tracker.(RevTracker); ok {
+		rt.RevUpdate(m)
+	}
+}
+
+func (p *Profiler) enter(name string, m Marker) {
+	stats, ok := p.stats[name]
+	if !ok {
+		stats = &RuleStats{Name: name}
+		p.stats[name] = stats
+	}
+	parent := p.samples
+	if n := len(p.stack); n > 0 {
+		parent = p.stack[n-1].sample
+	}
+	p.active[stats]++
+	p.stack = append(p.stack, profileFrame{
+		stats:    stats,
+		sample:   parent.child(name),
+		enterAt:  m,
+		furthest: m,
+		start:    p.now(),
+	})
+}
+
+func (p *Profiler) exit(m Marker, err error) {
+	n := len(p.stack)
+	f := p.stack[n-1]
+	p.stack = p.stack[:n-1]
+	total := p.now().Sub(f.start)
+	self := total - f.sub
+	back := 0
+	if f.furthest > m {
+		back = int(f.furthest - m)
+	}
+
+	f.stats.Calls++
+	if err == nil {
+		f.stats.Successes++
+		if m > f.enterAt {
+			f.stats.Consumed += int(m - f.enterAt)
+		}
+	} else {
+		f.stats.Failures++
+	}
+	f.stats.Backtracked += back
+	p.active[f.stats]--
+	if p.active[f.stats] == 0 {
+		f.stats.Total += total
+	}
+	f.stats.Self += self
+	f.sample.calls++
+	f.sample.self += self
+	f.sample.backtracked += back
+
+	if n > 1 {
+		parent := &p.stack[n-2]
+		parent.sub += total
+		if f.furthest > parent.furthest {
+			parent.furthest = f.furthest
+		}
+	}
+}
+
+// Stats returns the statistics of each called Rule, the Rules with the most self time first.
+func (p *Profiler) Stats() []RuleStats {
+	res := []RuleStats{}
+	for _, s := range p.stats {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Self != res[j].Self {
+			return res[i].Self > res[j].Self
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// WriteText writes the statistics as table to w.
+func (p *Profiler) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "rule\tcalls\tsuccesses\tfailures\tconsumed\tbacktracked\ttotal\tself\t")
+	for _, s := range p.Stats() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%v\t%v\t\n",
+			s.Name, s.Calls, s.Successes, s.Failures, s.Consumed, s.Backtracked, s.Total, s.Self)
+	}
+	return tw.Flush()
+}
+
+// ------------------------------------------------------------------------------
+type profileReader struct {
+	name string
+	p    *Profiler
+	sub  Reader
+}
+
+func (r *profileReader) Read(s *Scanner) error {
+	r.p.enter(r.name, s.Mark())
+	err := r.sub.Read(s)
+	r.p.exit(s.Mark(), err)
+	return err
+}
+
+func (r *profileReader) What() string {
+	return r.sub.What()
+}
+
+// Profile creates a Reader that reports the calls of r with the name to p.
+func Profile(r Reader, p *Profiler, name string) Reader {
+	return &profileReader{
+		name: name,
+		p:    p,
+		sub:  r,
+	}
+}
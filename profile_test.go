@@ -0,0 +1,156 @@
+package tok
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type profileGrammar struct {
+	List   Rule `name:"list"`
+	Item   Rule `name:"item"`
+	Number Rule `name:"number"`
+	Name   Rule `name:"name"`
+}
+
+func (g *profileGrammar) Read(s *Scanner) error {
+	return g.List.Read(s)
+}
+
+func (g *profileGrammar) What() string {
+	return "list"
+}
+
+func (g *profileGrammar) Grammar() []*Rule {
+	return CollectRules(g)
+}
+
+func newProfileGrammar() *profileGrammar {
+	g := &profileGrammar{}
+	MustSetRuleNames(g)
+	g.Number.Reader = Seq(Many(Digit()), Any(At(Rune(',')), AtEnd()))
+	g.Name.Reader = Many(BetweenAny("a-z0-9"))
+	g.Item.Reader = Any(&g.Number, &g.Name)
+	g.List.Reader = Seq(&g.Item, Zom(Seq(',', &g.Item)))
+	return g
+}
+
+func fakeClock() func() time.Time {
+	t := time.Unix(0, 0)
+	return func() time.Time {
+		t = t.Add(time.Millisecond)
+		return t
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	g := newProfileGrammar()
+	p := NewProfiler()
+	p.now = fakeClock()
+	p.Reset()
+	p.Profile(g.Grammar()...)
+	sca := NewScanner("12,12a,ab")
+	sca.Tracker = p
+	if err := sca.Use(g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := map[string]RuleStats{
+		"list":   {"list", 1, 1, 0, 9, 0, 17 * time.Millisecond, 4 * time.Millisecond},
+		"item":   {"item", 3, 3, 0, 7, 1, 13 * time.Millisecond, 8 * time.Millisecond},
+		"number": {"number", 3, 1, 2, 2, 3, 3 * time.Millisecond, 3 * time.Millisecond},
+		"name":   {"name", 2, 2, 0, 5, 0, 2 * time.Millisecond, 2 * time.Millisecond},
+	}
+	stats := p.Stats()
+	if len(stats) != len(exp) {
+		t.Fatalf("expected %d stats, got %d", len(exp), len(stats))
+	}
+	for _, s := range stats {
+		if s != exp[s.Name] {
+			t.Errorf("expected %v, got %v", exp[s.Name], s)
+		}
+	}
+
+	b := &strings.Builder{}
+	if err := p.WriteText(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "    rule  calls  successes") {
+		t.Errorf("unexpected text: %q", b.String())
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	g := newProfileGrammar()
+	p := ProfileGrammar(g)
+	sca := NewScanner("1,a")
+	sca.Tracker = p
+	if err := sca.Use(g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := p.WritePprof(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, str := range []string{"calls", "nanoseconds", "backtracked", "list", "item", "number", "name"} {
+		if !bytes.Contains(data, []byte(str)) {
+			t.Errorf("missing string %q in the profile", str)
+		}
+	}
+}
+
+func TestProfilerRecursion(t *testing.T) {
+	list := Rule{Name: "list"}
+	list.Reader = Seq('(', Zom(Any(&list, 'x')), ')')
+	p := NewProfiler()
+	p.now = fakeClock()
+	p.Reset()
+	p.Profile(&list)
+	if err := NewScanner("((x))").Use(&list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := p.Stats()[0]
+	if s.Calls != 5 || s.Total != 9*time.Millisecond || s.Self != s.Total {
+		t.Errorf("unexpected stats: %v", s)
+	}
+}
+
+func TestProfilerSamples(t *testing.T) {
+	g := newProfileGrammar()
+	p := ProfileGrammar(g)
+	if err := NewScanner("12,12a,ab").Use(g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res := []string{}
+	for _, s := range p.samples.collect(nil) {
+		res = append(res, fmt.Sprintf("%s %d", strings.Join(s.stack(), ";"), s.calls))
+	}
+	exp := "list 1|list;item 3|list;item;name 2|list;item;number 3"
+	if strings.Join(res, "|") != exp {
+		t.Errorf("unexpected samples: %s", strings.Join(res, "|"))
+	}
+}
+
+func BenchmarkProfiler(b *testing.B) {
+	g := newProfileGrammar()
+	ProfileGrammar(g)
+	text := strings.Repeat("12,ab,", 100) + "1"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := NewScanner(text).Use(g); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package tok
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -211,3 +213,123 @@ func (s *Scanner) ReadUint(base int, bitSize int) (uint64, error) {
 	s.Move(n + 1)
 	return u64, nil
 }
+
+// RevReadBool reads bool value from the scanner the reverse way.
+// The format values are the same as for ReadBool.
+func (s *Scanner) RevReadBool(format string) (bool, error) {
+	if format == "" || format == "*" {
+		if s.RevIfAny([]string{"true", "True", "TRUE"}) {
+			return true, nil
+		} else if s.RevIfAny([]string{"false", "False", "FALSE"}) {
+			return false, nil
+		}
+		return false, s.ErrorFor("bool")
+	}
+
+	trueStr, falseStr := "", ""
+	switch format {
+	case "l":
+		trueStr, falseStr = "true", "false"
+	case "U":
+		trueStr, falseStr = "TRUE", "FALSE"
+	case "Cc":
+		trueStr, falseStr = "True", "False"
+	default:
+		return false, fmt.Errorf("invalid format")
+	}
+
+	if s.RevIf(trueStr) {
+		return true, nil
+	} else if s.RevIf(falseStr) {
+		return false, nil
+	}
+	return false, s.ErrorFor("bool")
+}
+
+// revNumber returns the digits before the current position, with the sign before them if signed is true.
+// Returns an empty string if no digit is before the current position.
+func (s *Scanner) revNumber(charFunc func(rune) int32, signed bool) string {
+	head := s.Head()
+	from := len(head)
+	for from > 0 {
+		r, n := utf8.DecodeLastRuneInString(head[:from])
+		if charFunc(r) == -1 {
+			break
+		}
+		from -= n
+	}
+	if from == len(head) {
+		return ""
+	}
+	if signed && from > 0 && strings.ContainsRune("+-", rune(head[from-1])) {
+		from--
+	}
+	return head[from:]
+}
+
+// RevReadInt reads a integer value from the scanner the reverse way.
+// A sign before the digits is part of the value.
+// The base and bitSize values are the same as for ReadInt.
+// RevReadInt returns a ReadError if the value of all digits does not fit into bitSize.
+func (s *Scanner) RevReadInt(base int, bitSize int) (int64, error) {
+	var charFunc func(rune) int32
+	switch base {
+	case 8:
+		charFunc = octValue
+	case 10:
+		charFunc = decValue
+	case 16:
+		charFunc = hexValue
+	default:
+		return 0, fmt.Errorf("invalid base value %d", base)
+	}
+	switch bitSize {
+	case 8, 16, 32, 64:
+	default:
+		return 0, fmt.Errorf("invalid bitSize value %d", bitSize)
+	}
+
+	str := s.revNumber(charFunc, true)
+	if str == "" {
+		return 0, s.ErrorFor("integer")
+	}
+	i64, err := strconv.ParseInt(str, base, bitSize)
+	if err != nil {
+		return 0, s.ErrorFor("integer")
+	}
+	s.Move(-len(str))
+	return i64, nil
+}
+
+// RevReadUint reads a unsigned integer value from the scanner the reverse way.
+// The base and bitSize values are the same as for ReadUint.
+// RevReadUint returns a ReadError if the value of all digits does not fit into bitSize.
+func (s *Scanner) RevReadUint(base int, bitSize int) (uint64, error) {
+	var charFunc func(rune) int32
+	switch base {
+	case 8:
+		charFunc = octValue
+	case 10:
+		charFunc = decValue
+	case 16:
+		charFunc = hexValue
+	default:
+		return 0, fmt.Errorf("invalid base value %d", base)
+	}
+	switch bitSize {
+	case 8, 16, 32, 64:
+	default:
+		return 0, fmt.Errorf("invalid bitSize value %d", bitSize)
+	}
+
+	str := s.revNumber(charFunc, false)
+	if str == "" {
+		return 0, s.ErrorFor("unsigned integer")
+	}
+	u64, err := strconv.ParseUint(str, base, bitSize)
+	if err != nil {
+		return 0, s.ErrorFor("unsigned integer")
+	}
+	s.Move(-len(str))
+	return u64, nil
+}
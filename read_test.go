@@ -133,3 +133,141 @@ func TestReadUint(t *testing.T) {
 		}
 	}
 }
+
+func TestRevReadInt(t *testing.T) {
+	cases := []struct {
+		inp     string
+		base    int
+		bitSize int
+		exp     int64
+		head    string
+	}{
+		{"line 22", 10, 8, 22, "line "},
+		{"v-70", 8, 8, -56, "v"},
+		{"x=-128", 10, 8, -128, "x="},
+		{"id:30df", 16, 16, 12511, "id:"},
+		{"9223372036854775807", 10, 64, math.MaxInt64, ""},
+	}
+	for i, c := range cases {
+		sca := NewRevScanner(c.inp)
+		val, err := sca.RevReadInt(c.base, c.bitSize)
+		if err != nil {
+			t.Errorf("%d %q unexpected error: %v", i, c.inp, err)
+		} else if val != c.exp {
+			t.Errorf("%d unexpected result: %d != %d", i, val, c.exp)
+		} else if sca.Head() != c.head {
+			t.Errorf("%d %q scanner at wrong positiong, head >%s<", i, c.inp, sca.Head())
+		}
+	}
+
+	failed := []string{"abcd-", "", "12 "}
+	for i, f := range failed {
+		sca := NewRevScanner(f)
+		_, err := sca.RevReadInt(10, 64)
+		if err == nil {
+			t.Errorf("%d expected error for %s", i, f)
+		}
+		if !sca.AtEnd() {
+			t.Errorf("%d scanner was moved for %s", i, f)
+		}
+	}
+
+	overflow := []struct {
+		inp     string
+		bitSize int
+	}{
+		{"x=-129", 8},
+		{"x=128", 8},
+		{"9223372036854775808", 64},
+	}
+	for i, o := range overflow {
+		sca := NewRevScanner(o.inp)
+		_, err := sca.RevReadInt(10, o.bitSize)
+		if _, ok := err.(ReadError); !ok {
+			t.Errorf("%d expected a ReadError for %s: %v", i, o.inp, err)
+		}
+		if !sca.AtEnd() {
+			t.Errorf("%d scanner was moved for %s", i, o.inp)
+		}
+	}
+}
+
+func TestRevReadUint(t *testing.T) {
+	cases := []struct {
+		inp     string
+		base    int
+		bitSize int
+		exp     uint64
+		head    string
+	}{
+		{"size 255", 10, 8, 255, "size "},
+		{"-42", 10, 64, 42, "-"},
+		{"#F0", 16, 8, 240, "#"},
+	}
+	for i, c := range cases {
+		sca := NewRevScanner(c.inp)
+		val, err := sca.RevReadUint(c.base, c.bitSize)
+		if err != nil {
+			t.Errorf("%d %q unexpected error: %v", i, c.inp, err)
+		} else if val != c.exp {
+			t.Errorf("%d unexpected result: %d != %d", i, val, c.exp)
+		} else if sca.Head() != c.head {
+			t.Errorf("%d scanner at wrong positiong, head >%s<", i, sca.Head())
+		}
+	}
+
+	overflow := []struct {
+		inp  string
+		base int
+	}{
+		{"size 256", 10},
+		{"#1FF", 16},
+	}
+	for i, o := range overflow {
+		sca := NewRevScanner(o.inp)
+		_, err := sca.RevReadUint(o.base, 8)
+		if _, ok := err.(ReadError); !ok {
+			t.Errorf("%d expected a ReadError for %s: %v", i, o.inp, err)
+		}
+		if !sca.AtEnd() {
+			t.Errorf("%d scanner was moved for %s", i, o.inp)
+		}
+	}
+}
+
+func TestRevReadInvalidArgs(t *testing.T) {
+	sca := NewRevScanner("123")
+	if _, err := sca.RevReadInt(10, 12); err == nil {
+		t.Errorf("expected an error for bitSize 12")
+	}
+	if _, err := sca.RevReadUint(10, 0); err == nil {
+		t.Errorf("expected an error for bitSize 0")
+	}
+	if !sca.AtEnd() {
+		t.Errorf("scanner was moved")
+	}
+}
+
+func TestRevReadBool(t *testing.T) {
+	cases := []struct {
+		inp    string
+		format string
+		exp    bool
+		head   string
+	}{
+		{"enabled=true", "", true, "enabled="},
+		{"enabled=FALSE", "U", false, "enabled="},
+		{"enabled=False", "Cc", false, "enabled="},
+	}
+	for i, c := range cases {
+		sca := NewRevScanner(c.inp)
+		val, err := sca.RevReadBool(c.format)
+		if err != nil {
+			t.Errorf("%d %q unexpected error: %v", i, c.inp, err)
+		} else if val != c.exp {
+			t.Errorf("%d unexpected result: %v != %v", i, val, c.exp)
+		} else if sca.Head() != c.head {
+			t.Errorf("%d scanner at wrong positiong, head >%s<", i, sca.Head())
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 )
 
 func asReader(i interface{}) (Reader, bool) {
@@ -34,7 +33,7 @@ type Reader interface {
 // The scanner is only moved if no error occurs.
 func (s *Scanner) Use(r Reader) error {
 	m := s.Mark()
-	err := r.Read(s)
+	err := s.read(r.Read)
 	if err != nil {
 		s.ToMarker(m)
 	}
@@ -45,7 +44,7 @@ func (s *Scanner) Use(r Reader) error {
 // The scanner is only moved if no error occurs.
 func (s *Scanner) UseFunc(f ReadFunc) error {
 	m := s.Mark()
-	err := f(s)
+	err := s.read(f)
 	if err != nil {
 		s.ToMarker(m)
 	}
@@ -55,7 +54,7 @@ func (s *Scanner) UseFunc(f ReadFunc) error {
 // TraceUse traces the readed sub string.
 func (s *Scanner) TraceUse(r Reader) (string, error) {
 	m := s.Mark()
-	err := r.Read(s)
+	err := s.read(r.Read)
 	if err != nil {
 		s.ToMarker(m)
 	}
@@ -65,7 +64,7 @@ func (s *Scanner) TraceUse(r Reader) (string, error) {
 // TraceUseFunc traces the via f traced sub string.
 func (s *Scanner) TraceUseFunc(f ReadFunc) (string, error) {
 	m := s.Mark()
-	err := f(s)
+	err := s.read(f)
 	if err != nil {
 		s.ToMarker(m)
 	}
@@ -77,12 +76,17 @@ type anyReader struct {
 	readers []Reader
 }
 
-func getDeepest(errs []error) error {
+func getDeepest(errs []error, rev bool) error {
 	var deepest ReadError
+	found := false
 	for _, e := range errs {
 		re, ok := e.(ReadError)
-		if ok && re.Later(deepest) {
+		if !ok {
+			continue
+		}
+		if !found || re.Later(deepest) != rev {
 			deepest = re
+			found = true
 		}
 	}
 	return deepest
@@ -99,7 +103,7 @@ func (r *anyReader) Read(s *Scanner) error {
 		}
 	}
 	s.ToMarker(m)
-	return getDeepest(errs)
+	return getDeepest(errs, s.Reversed())
 }
 
 func (r *anyReader) What() string {
@@ -141,6 +145,9 @@ type anyRuneReader struct {
 }
 
 func (r *anyRuneReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIfAnyRune(r.str), r.What())
+	}
 	return s.ErrorIfFalse(s.IfAnyRune(r.str), r.What())
 }
 
@@ -180,7 +187,7 @@ type atEndReader struct {
 }
 
 func (r atEndReader) Read(s *Scanner) error {
-	return s.ErrorIfFalse(s.AtEnd(), r.What())
+	return s.ErrorIfFalse(s.atLimit(), r.What())
 }
 
 func (r atEndReader) What() string {
@@ -188,10 +195,62 @@ func (r atEndReader) What() string {
 }
 
 // At creates a Reader that checks the scanner reaches the end.
+// In reverse direction checks the Reader that the scanner reaches the start.
 func AtEnd() Reader {
 	return atEndReader{}
 }
 
+// ------------------------------------------------------------------------------
+// readBehind checks if r can read in the opposite direction from the current position.
+// The scanner is not moved and the Tracker is not informed about the check.
+func readBehind(s *Scanner, r Reader) bool {
+	m := s.Mark()
+	tracker := s.Tracker
+	s.Tracker = nil
+	err := Rev(r).Read(s)
+	s.ToMarker(m)
+	s.Tracker = tracker
+	return err == nil
+}
+
+type behindReader struct {
+	sub Reader
+}
+
+func (r *behindReader) Read(s *Scanner) error {
+	return s.ErrorIfFalse(readBehind(s, r.sub), r.What())
+}
+
+func (r *behindReader) What() string {
+	return "@<" + r.sub.What()
+}
+
+// Behind creates a Reader that checks if r matches the text before the current position of the scanner.
+// The Reader r reads in the opposite direction, see Rev.
+// The Reader does not move the scanner.
+func Behind(r Reader) Reader {
+	return &behindReader{r}
+}
+
+// ------------------------------------------------------------------------------
+type notBehindReader struct {
+	sub Reader
+}
+
+func (r *notBehindReader) Read(s *Scanner) error {
+	return s.ErrorIfFalse(!readBehind(s, r.sub), r.What())
+}
+
+func (r *notBehindReader) What() string {
+	return "!<" + r.sub.What()
+}
+
+// NotBehind creates a Reader that checks if r does not match the text before the current position of the scanner.
+// The Reader does not move the scanner.
+func NotBehind(r Reader) Reader {
+	return &notBehindReader{r}
+}
+
 // ------------------------------------------------------------------------------
 type betweenReader struct {
 	min rune
@@ -199,6 +258,9 @@ type betweenReader struct {
 }
 
 func (r betweenReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIfBetween(r.min, r.max), r.What())
+	}
 	return s.ErrorIfFalse(s.IfBetween(r.min, r.max), r.What())
 }
 
@@ -231,12 +293,16 @@ type betweenAnyReader struct {
 
 func (r *betweenAnyReader) Read(s *Scanner) error {
 	m := s.Mark()
+	ifBetween, ifAnyRune := s.IfBetween, s.IfAnyRune
+	if s.Reversed() {
+		ifBetween, ifAnyRune = s.RevIfBetween, s.RevIfAnyRune
+	}
 	for i := 0; i < len(r.min); i++ {
-		if s.IfBetween(r.min[i], r.max[i]) {
+		if ifBetween(r.min[i], r.max[i]) {
 			return nil
 		}
 	}
-	if s.IfAnyRune(r.singles) {
+	if ifAnyRune(r.singles) {
 		return nil
 	}
 	s.ToMarker(m)
@@ -315,6 +381,12 @@ func BuildBetweenAny(minMax ...rune) Reader {
 }
 
 // ------------------------------------------------------------------------------
+// readsBody checks if body reads the full sub string that t marks in the reading direction of s.
+func readsBody(s *Scanner, body Reader, t Token) bool {
+	sub := s.Window(t)
+	return sub.Use(body) == nil && sub.atLimit()
+}
+
 type bodyReader struct {
 	body Reader
 	tail Reader
@@ -322,12 +394,10 @@ type bodyReader struct {
 
 func (r *bodyReader) Read(s *Scanner) error {
 	m := s.Mark()
-	for ; !s.AtEnd(); s.MoveRunes(1) {
+	for ok := !s.atLimit(); ok; ok = s.moveOn(1) && !s.atLimit() {
 		t := s.Mark()
 		if e := r.tail.Read(s); e == nil {
-			sub := NewScanner(s.Get(MakeToken(m, t)))
-			e = sub.Use(r.body)
-			if e != nil || !sub.AtEnd() {
+			if !readsBody(s, r.body, MakeToken(m, t)) {
 				break
 			}
 			s.ToMarker(t)
@@ -355,12 +425,15 @@ type bodyTailReader struct {
 
 func (r *bodyTailReader) Read(s *Scanner) error {
 	m := s.Mark()
-	for ; !s.AtEnd(); s.MoveRunes(1) {
+	for ok := !s.atLimit(); ok; ok = s.moveOn(1) && !s.atLimit() {
 		t := s.Mark()
 		if e := r.tail.Read(s); e == nil {
-			sub := NewScanner(s.Get(MakeToken(m, t)))
-			e = sub.Use(r.body)
-			if e != nil || !sub.AtEnd() {
+			if !readsBody(s, r.body, MakeToken(m, t)) {
+				break
+			}
+			// reads tail again, the Tracker dropped the values of tail while body was read
+			s.ToMarker(t)
+			if e = r.tail.Read(s); e != nil {
 				break
 			}
 			return nil
@@ -387,7 +460,11 @@ type BoolReader struct {
 }
 
 func (r *BoolReader) Read(s *Scanner) error {
-	v, err := s.ReadBool(r.Format)
+	read := s.ReadBool
+	if s.Reversed() {
+		read = s.RevReadBool
+	}
+	v, err := read(r.Format)
 	r.Value = v
 	return err
 }
@@ -421,6 +498,9 @@ type foldReader struct {
 }
 
 func (r foldReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIfFold(r.val), r.What())
+	}
 	return s.ErrorIfFalse(s.IfFold(r.val), r.What())
 }
 
@@ -447,11 +527,13 @@ type holeyReader struct {
 }
 
 func (r holeyReader) Read(s *Scanner) error {
-	val, i := utf8.DecodeRuneInString(s.Tail())
-	if inRange(r.min, val, r.max) && !strings.ContainsRune(r.holes, val) {
-		return s.ErrorIfFalse(s.Move(i), r.What())
+	check := func(val rune) bool {
+		return inRange(r.min, val, r.max) && !strings.ContainsRune(r.holes, val)
 	}
-	return s.ErrorFor(r.What())
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIfMatch(check), r.What())
+	}
+	return s.ErrorIfFalse(s.IfMatch(check), r.What())
 }
 
 func (r holeyReader) What() string {
@@ -472,7 +554,11 @@ type IntReader struct {
 }
 
 func (r *IntReader) Read(s *Scanner) error {
-	v, err := s.ReadInt(r.Base, r.BitSize)
+	read := s.ReadInt
+	if s.Reversed() {
+		read = s.RevReadInt
+	}
+	v, err := read(r.Base, r.BitSize)
 	r.Value = v
 	return err
 }
@@ -499,11 +585,10 @@ type janusBeginReader struct {
 }
 
 func (r *janusBeginReader) Read(s *Scanner) error {
-	t, err := s.TokenizeUse(r.reader)
-	if err == nil {
-		r.end.reader.str = s.Get(t)
+	if s.Reversed() {
+		return r.end.match(s)
 	}
-	return err
+	return r.end.capture(s, r.reader)
 }
 
 func (r *janusBeginReader) What() string {
@@ -512,10 +597,32 @@ func (r *janusBeginReader) What() string {
 
 type janusEndReader struct {
 	reader litReader
+	sub    Reader
 	name   string
 }
 
 func (r *janusEndReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return r.capture(s, r.sub)
+	}
+	return r.match(s)
+}
+
+// capture reads with sub and stores the read sub string for match.
+func (r *janusEndReader) capture(s *Scanner, sub Reader) error {
+	t, err := s.TokenizeUse(sub)
+	if err == nil {
+		r.reader.str = s.Get(t)
+	}
+	return err
+}
+
+// match reads the captured sub string and resets it.
+// A lookTracker learns that match looks at the length of the captured sub string.
+func (r *janusEndReader) match(s *Scanner) error {
+	if t, ok := s.Tracker.(lookTracker); ok && !s.Reversed() {
+		t.look(s.Mark() + Marker(len(r.reader.str)))
+	}
 	err := r.reader.Read(s)
 	if err == nil {
 		r.reader.str = ""
@@ -530,9 +637,11 @@ func (r *janusEndReader) What() string {
 // Janus creates two Reader.
 // The first one tries to match with r.
 // If the first matches expects the second the matched sub string.
+// In reverse direction the second one matches with r and the first one expects the matched sub string.
 func Janus(name string, r Reader) (Reader, Reader) {
 	end := &janusEndReader{
 		reader: litReader{""},
+		sub:    r,
 		name:   name,
 	}
 	beg := &janusBeginReader{
@@ -588,6 +697,9 @@ type litReader struct {
 }
 
 func (r litReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIf(r.str), r.What())
+	}
 	return s.ErrorIfFalse(s.If(r.str), r.What())
 }
 
@@ -610,7 +722,7 @@ func (r manyReader) Read(s *Scanner) error {
 	for r.sub.Read(s) == nil {
 	}
 	end := s.Mark()
-	return s.ErrorIfFalse(start < end, r.What())
+	return s.ErrorIfFalse(start != end, r.What())
 }
 
 func (r manyReader) What() string {
@@ -660,6 +772,9 @@ type matchReader struct {
 }
 
 func (r matchReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIfMatch(r.f), r.what)
+	}
 	return s.ErrorIfFalse(s.IfMatch(r.f), r.what)
 }
 
@@ -709,7 +824,7 @@ func (r *notReader) Read(s *Scanner) error {
 	if err == nil {
 		return s.ErrorFor(r.sub.What())
 	}
-	s.MoveRunes(1)
+	s.moveOn(1)
 	return nil
 }
 
@@ -718,6 +833,7 @@ func (r *notReader) What() string {
 }
 
 // Not creates a Reader that moves 1 Rune forward if r does not match.
+// At the end of the text the Reader matches without moving, add AtEnd to r to stop a repetition there.
 func Not(r Reader) Reader {
 	return &notReader{r}
 }
@@ -753,7 +869,7 @@ type pastReader struct {
 
 func (r *pastReader) Read(s *Scanner) error {
 	m := s.Mark()
-	for ; !s.AtEnd(); s.MoveRunes(1) {
+	for ok := !s.atLimit(); ok; ok = s.moveOn(1) && !s.atLimit() {
 		if e := r.sub.Read(s); e == nil {
 			return nil
 		}
@@ -776,12 +892,114 @@ func Past(i interface{}) Reader {
 	return &pastReader{r}
 }
 
+// ------------------------------------------------------------------------------
+// orderTokens reverses tokens if s reads in reverse direction.
+// The Tokens are afterwards in the order of the text.
+func orderTokens(s *Scanner, tokens []Token) {
+	if !s.Reversed() {
+		return
+	}
+	for i, j := 0, len(tokens)-1; i < j; i, j = i+1, j-1 {
+		tokens[i], tokens[j] = tokens[j], tokens[i]
+	}
+}
+
+// RepeatReader is a Reader that stores the Tokens of the readed items in the field Items.
+type RepeatReader struct {
+	Items []Token
+	Min   int
+	Max   int
+	sub   Reader
+}
+
+func (r *RepeatReader) Read(s *Scanner) error {
+	m := s.Mark()
+	r.Items = []Token{}
+	var err error
+	for r.Max < 0 || len(r.Items) < r.Max {
+		var t Token
+		t, err = s.TokenizeUse(r.sub)
+		if err != nil {
+			break
+		}
+		r.Items = append(r.Items, t)
+		if t.Len() == 0 {
+			break
+		}
+	}
+	if len(r.Items) < r.Min {
+		s.ToMarker(m)
+		r.Items = []Token{}
+		if err != nil {
+			return err
+		}
+		return s.ErrorFor(r.What())
+	}
+	orderTokens(s, r.Items)
+	return nil
+}
+
+// Count returns the number of items that the last Read call has read.
+func (r *RepeatReader) Count() int {
+	return len(r.Items)
+}
+
+func (r *RepeatReader) What() string {
+	if r.Max < 0 {
+		return fmt.Sprintf("{%d,}*%s", r.Min, r.sub.What())
+	}
+	return fmt.Sprintf("{%d,%d}*%s", r.Min, r.Max, r.sub.What())
+}
+
+// Repeat creates a Reader that expects that i matches at least min and at most max times.
+// A negative max value allows an unlimited number of matches.
+// The type of i can be rune, string or Reader.
+// The Reader is invalid if min is greater than a not negative max, CheckRules reports it.
+func Repeat(min int, max int, i interface{}) *RepeatReader {
+	r, ok := asReader(i)
+	if !ok {
+		r = InvalidReader("invalid Repeat parameter: unknown type %T", i)
+	} else if max >= 0 && min > max {
+		r = InvalidReader("invalid Repeat parameters: min %d is greater than max %d", min, max)
+	}
+	return &RepeatReader{
+		Min: min,
+		Max: max,
+		sub: r,
+	}
+}
+
+// ------------------------------------------------------------------------------
+type revReader struct {
+	sub Reader
+}
+
+func (r *revReader) Read(s *Scanner) error {
+	s.rev = !s.rev
+	err := r.sub.Read(s)
+	s.rev = !s.rev
+	return err
+}
+
+func (r *revReader) What() string {
+	return "<" + r.sub.What()
+}
+
+// Rev creates a Reader that reads with r in the opposite direction.
+// In reverse direction reads each Reader backward and Seq applies the readers from the last to the first.
+func Rev(r Reader) Reader {
+	return &revReader{r}
+}
+
 // ------------------------------------------------------------------------------
 type runeReader struct {
 	r rune
 }
 
 func (r runeReader) Read(s *Scanner) error {
+	if s.Reversed() {
+		return s.ErrorIfFalse(s.RevIfRune(r.r), r.What())
+	}
 	return s.ErrorIfFalse(s.IfRune(r.r), r.What())
 }
 
@@ -795,6 +1013,115 @@ func Rune(r rune) Reader {
 }
 
 // ------------------------------------------------------------------------------
+// SepByReader is a Reader that stores the Tokens of the readed items in the field Items.
+type SepByReader struct {
+	Items []Token
+	Min   int
+	Trail bool
+	item  Reader
+	sep   Reader
+}
+
+func (r *SepByReader) Read(s *Scanner) error {
+	m := s.Mark()
+	r.Items = []Token{}
+	t, err := s.TokenizeUse(r.item)
+	if err == nil {
+		r.Items = append(r.Items, t)
+		for {
+			sepM := s.Mark()
+			if s.Use(r.sep) != nil {
+				break
+			}
+			t, e := s.TokenizeUse(r.item)
+			if e != nil {
+				if !r.Trail {
+					s.ToMarker(sepM)
+				}
+				break
+			}
+			r.Items = append(r.Items, t)
+			if s.Mark() == sepM {
+				break
+			}
+		}
+	}
+	if len(r.Items) < r.Min {
+		s.ToMarker(m)
+		r.Items = []Token{}
+		if err != nil {
+			return err
+		}
+		return s.ErrorFor(r.What())
+	}
+	orderTokens(s, r.Items)
+	return nil
+}
+
+// Count returns the number of items that the last Read call has read.
+func (r *SepByReader) Count() int {
+	return len(r.Items)
+}
+
+func (r *SepByReader) What() string {
+	prefix := "*"
+	if r.Min > 0 {
+		prefix = "+"
+	}
+	suffix := ""
+	if r.Trail {
+		suffix = "?"
+	}
+	return fmt.Sprintf("%s(%s %% %s%s)", prefix, r.item.What(), r.sep.What(), suffix)
+}
+
+func sepBy(name string, item, sep interface{}) *SepByReader {
+	r := &SepByReader{}
+	var ok bool
+	if r.item, ok = asReader(item); !ok {
+		r.item = InvalidReader("invalid %s item parameter: unknown type %T", name, item)
+	}
+	if r.sep, ok = asReader(sep); !ok {
+		r.sep = InvalidReader("invalid %s sep parameter: unknown type %T", name, sep)
+	}
+	return r
+}
+
+// SepBy creates a Reader that expects zero or more items separated by sep.
+// The type of item and sep can be rune, string or Reader.
+// See SepBy1 for a Reader that expects one or more.
+func SepBy(item, sep interface{}) *SepByReader {
+	return sepBy("SepBy", item, sep)
+}
+
+// SepBy1 creates a Reader that expects one or more items separated by sep.
+func SepBy1(item, sep interface{}) *SepByReader {
+	r := sepBy("SepBy1", item, sep)
+	r.Min = 1
+	return r
+}
+
+// SepEndBy creates a Reader that expects zero or more items separated by sep,
+// a separator after the last item is allowed.
+func SepEndBy(item, sep interface{}) *SepByReader {
+	r := sepBy("SepEndBy", item, sep)
+	r.Trail = true
+	return r
+}
+
+// ------------------------------------------------------------------------------
+// orderReaders returns the readers in the order of the current reading direction.
+func orderReaders(s *Scanner, readers []Reader) []Reader {
+	if !s.Reversed() {
+		return readers
+	}
+	rev := make([]Reader, len(readers))
+	for i, r := range readers {
+		rev[len(readers)-1-i] = r
+	}
+	return rev
+}
+
 type seqReader struct {
 	readers []Reader
 }
@@ -802,7 +1129,7 @@ type seqReader struct {
 func (r *seqReader) Read(s *Scanner) error {
 	m := s.Mark()
 	var err error
-	for _, sub := range r.readers {
+	for _, sub := range orderReaders(s, r.readers) {
 		if e := sub.Read(s); e != nil {
 			s.ToMarker(m)
 			err = e
@@ -843,7 +1170,7 @@ type skipSeqReader struct {
 func (r *skipSeqReader) Read(s *Scanner) error {
 	m := s.Mark()
 	err := r.skip.Read(s)
-	for _, sub := range r.readers {
+	for _, sub := range orderReaders(s, r.readers) {
 		if err != nil {
 			break
 		}
@@ -920,7 +1247,7 @@ type toReader struct {
 
 func (r *toReader) Read(s *Scanner) error {
 	m := s.Mark()
-	for ok := true; ok; ok = s.MoveRunes(1) {
+	for ok := true; ok; ok = s.moveOn(1) {
 		subM := s.Mark()
 		if e := r.sub.Read(s); e == nil {
 			s.ToMarker(subM)
@@ -954,7 +1281,11 @@ type UintReader struct {
 }
 
 func (r *UintReader) Read(s *Scanner) error {
-	v, err := s.ReadUint(r.Base, r.BitSize)
+	read := s.ReadUint
+	if s.Reversed() {
+		read = s.RevReadUint
+	}
+	v, err := read(r.Base, r.BitSize)
 	r.Value = v
 	return err
 }
@@ -49,6 +49,13 @@ func TestReaderWhat(t *testing.T) {
 		{Fold("true"), `~"true"`},
 		{Holey('a', 'z', "ox"), `(<az> - "ox")`},
 		{Not(Rune('A')), `!'A'`},
+		{Behind(Digit()), `@<<09>`},
+		{NotBehind(Lit("--")), `!<"--"`},
+		{Repeat(2, 4, Digit()), `{2,4}*<09>`},
+		{Repeat(1, -1, Digit()), `{1,}*<09>`},
+		{SepBy(Digit(), Rune(',')), `*(<09> % ',')`},
+		{SepBy1(Digit(), Rune(',')), `+(<09> % ',')`},
+		{SepEndBy(Digit(), Rune(',')), `*(<09> % ','?)`},
 		{Seq(Rune('!'), Many(AnyRune(" +-")), Lit("abc")), `'!' +[" +-"] "abc"`},
 		{To(Bool("")), `->bool{""}`},
 		{Uint(16, 64), `uint{16,64}`},
@@ -83,6 +90,18 @@ func TestJanus(t *testing.T) {
 
 	str, err = NewScanner("[==[long lua string]==] ~=").CaptureUse(Seq(comBeg, Past(comEnd)))
 	check(str, "[==[long lua string]==]", err, false)
+
+	long := Seq('[', beg, '[', Many(Between('a', 'z')), ']', end, ']')
+	sca := NewRevScanner("x[==[abc]==]")
+	if err := sca.RevUse(long); err != nil || sca.Head() != "x" {
+		t.Errorf("unexpected result: %v, head %q", err, sca.Head())
+	}
+	for i, inp := range []string{"[==[abc]=]", "[=[abc]==]"} {
+		sca := NewRevScanner(inp)
+		if err := sca.RevUse(long); err == nil {
+			t.Errorf("%d expected an error for %s", i, inp)
+		}
+	}
 }
 
 func TestNot(t *testing.T) {
@@ -103,6 +122,15 @@ func TestNot(t *testing.T) {
 
 	str, err = NewScanner("XYZAa").CaptureUse(Many(notA))
 	check(str, "XYZ", err, false)
+
+	sca := NewScanner("")
+	if err := sca.Use(notA); err != nil || !sca.AtEnd() {
+		t.Errorf("unexpected result at the end: %v", err)
+	}
+	str, err = NewScanner("XYZ").CaptureUse(Many(Not(Any(AnyRune("aA"), AtEnd()))))
+	if str != "XYZ" || err != nil {
+		t.Errorf("unexpected result: %q, %v", str, err)
+	}
 }
 
 func TestInvalidReader(t *testing.T) {
@@ -115,4 +143,187 @@ func TestInvalidReader(t *testing.T) {
 	if HasInvalidReader(r.What()) {
 		t.Errorf("unexpected %s in: %s", InvalidReaderMarker, r.What())
 	}
+	for i, r := range []Reader{SepBy(12, ','), SepEndBy('a', 1.5), Repeat(1, 2, true), Repeat(2, 1, 'a')} {
+		if !HasInvalidReader(r.What()) {
+			t.Errorf("%d expected %s in: %s", i, InvalidReaderMarker, r.What())
+		}
+	}
+}
+
+func TestSepBy(t *testing.T) {
+	cases := []struct {
+		inp   string
+		r     *SepByReader
+		count int
+		tail  string
+		err   bool
+	}{
+		{"a,b,c", SepBy(Between('a', 'z'), Rune(',')), 3, "", false},
+		{"a,b,", SepBy(Between('a', 'z'), Rune(',')), 2, ",", false},
+		{"1,b", SepBy(Between('a', 'z'), Rune(',')), 0, "1,b", false},
+		{"a;b", SepBy1(Between('a', 'z'), Rune(',')), 1, ";b", false},
+		{"1,b", SepBy1(Between('a', 'z'), Rune(',')), 0, "1,b", true},
+		{"a, b, c, ]", SepEndBy(Between('a', 'z'), Lit(", ")), 3, "]", false},
+		{"a, b, c]", SepEndBy(Between('a', 'z'), Lit(", ")), 3, "]", false},
+		{"a,b", &SepByReader{Min: 3, item: Between('a', 'z'), sep: Rune(',')}, 0, "a,b", true},
+		{"a;b;c", SepBy(Between('a', 'z'), ';'), 3, "", false},
+		{"x--x", SepBy('x', "--"), 2, "", false},
+	}
+	for i, c := range cases {
+		sca := NewScanner(c.inp)
+		err := sca.Use(c.r)
+		if (err != nil) != c.err {
+			t.Errorf("%d unexpected error value: %v", i, err)
+		}
+		if c.r.Count() != c.count {
+			t.Errorf("%d unexpected count: %d != %d", i, c.r.Count(), c.count)
+		}
+		if c.tail != sca.Tail() {
+			t.Errorf("%d unexpected tail value: %q != %q", i, c.tail, sca.Tail())
+		}
+	}
+
+	r := SepBy(Many(Digit()), Rune(','))
+	sca := NewScanner("12,3,456")
+	if err := sca.Use(r); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	exp := []string{"12", "3", "456"}
+	for i, item := range r.Items {
+		if sca.Get(item) != exp[i] {
+			t.Errorf("%d unexpected item: %q != %q", i, sca.Get(item), exp[i])
+		}
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	cases := []struct {
+		inp   string
+		r     *RepeatReader
+		count int
+		tail  string
+		err   bool
+	}{
+		{"abcdef", Repeat(2, 4, Between('a', 'z')), 4, "ef", false},
+		{"ab1", Repeat(2, 4, Between('a', 'z')), 2, "1", false},
+		{"a1", Repeat(2, 4, Between('a', 'z')), 0, "a1", true},
+		{"abcdef", Repeat(0, -1, Between('a', 'z')), 6, "", false},
+		{"1", Repeat(0, 3, Between('a', 'z')), 0, "1", false},
+		{"abc", Repeat(1, -1, Opt(Digit())), 1, "abc", false},
+		{"aab", Repeat(1, 2, 'a'), 2, "b", false},
+	}
+	for i, c := range cases {
+		sca := NewScanner(c.inp)
+		err := sca.Use(c.r)
+		if (err != nil) != c.err {
+			t.Errorf("%d unexpected error value: %v", i, err)
+		}
+		if c.r.Count() != c.count {
+			t.Errorf("%d unexpected count: %d != %d", i, c.r.Count(), c.count)
+		}
+		if c.tail != sca.Tail() {
+			t.Errorf("%d unexpected tail value: %q != %q", i, c.tail, sca.Tail())
+		}
+	}
+}
+
+func TestBehind(t *testing.T) {
+	minus := Seq(NotBehind(Digit()), Rune('-'))
+	cases := []struct {
+		inp  string
+		move int
+		r    Reader
+		tail string
+		err  bool
+	}{
+		{"12-3", 2, minus, "12-3", true},
+		{"x -3", 2, minus, "3", false},
+		{"-3", 0, minus, "3", false},
+		{"abc:def", 4, Behind(Lit("abc:")), "def", false},
+		{"abc:def", 4, Behind(Seq(Many(Between('a', 'z')), Rune(':'))), "def", false},
+		{"abc:def", 3, Behind(Lit("abc:")), ":def", true},
+		{"abc:def", 0, Behind(Lit("abc")), "abc:def", true},
+		{"äöü", 4, Behind(Lit("ö")), "ü", false},
+	}
+	for i, c := range cases {
+		sca := NewScanner(c.inp)
+		sca.Move(c.move)
+		err := sca.Use(c.r)
+		if (err != nil) != c.err {
+			t.Errorf("%d unexpected error value: %v", i, err)
+		}
+		if err != nil {
+			continue
+		}
+		if c.tail != sca.Tail() {
+			t.Errorf("%d unexpected tail value: %q != %q", i, c.tail, sca.Tail())
+		}
+	}
+}
+
+func TestRev(t *testing.T) {
+	ext := Seq('.', Many(Set("a-z0-9", "")))
+	version := Seq('v', Many(Digit()), Zom(Seq('.', Many(Digit()))))
+	cases := []struct {
+		inp  string
+		r    Reader
+		head string
+	}{
+		{"archive.tar.gz", ext, "archive.tar"},
+		{"tok-v1.12.3", version, "tok-"},
+		{"a, b, c", SepBy(Between('a', 'z'), Lit(", ")), ""},
+		{"key = value", SkipWSSeq(Many(Between('a', 'z')), '=', Many(Between('a', 'z'))), ""},
+		{"x[abc]", Seq(Past('['), ']'), "x"},
+		{"x := 12", Seq(Any(Lit(":="), Rune('=')), WS(), Int(10, 64)), "x "},
+		{"#ok", Seq(AtEnd(), '#', Fold("OK")), ""},
+	}
+	for i, c := range cases {
+		sca := NewRevScanner(c.inp)
+		err := sca.RevUse(c.r)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+		if c.head != sca.Head() {
+			t.Errorf("%d unexpected head value: %q != %q", i, c.head, sca.Head())
+		}
+		if sca.Reversed() {
+			t.Errorf("%d scanner is still reversed", i)
+		}
+	}
+
+	r := SepBy(Many(Digit()), Rune(','))
+	sca := NewRevScanner("12,3,456")
+	if err := sca.RevUse(r); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	exp := []string{"12", "3", "456"}
+	for i, item := range r.Items {
+		if sca.Get(item) != exp[i] {
+			t.Errorf("%d unexpected item: %q != %q", i, sca.Get(item), exp[i])
+		}
+	}
+}
+
+func TestBodyWindow(t *testing.T) {
+	sca := NewScanner("x = abc;")
+	basket := sca.NewBasket()
+	word := Pick(Many(Between('a', 'z')), basket, "word")
+	semi := Pick(Rune(';'), basket, "semi")
+	err := sca.Use(Seq("x = ", BodyTail(word, semi)))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if basket.String() != "word[4-7);semi[7-8)" {
+		t.Errorf("unexpected picked values: %s", basket.String())
+	}
+
+	log := &Log{}
+	sca = NewScanner("x = ab1;")
+	err = sca.Use(Seq("x = ", Body(Monitor(Seq(Many(Between('a', 'z')), Digit()), log, "word"), Rune(';'))))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(log.Entries) != 1 || log.Entries[0].EnterAt != 4 || log.Entries[0].ExitAt != 7 {
+		t.Errorf("unexpected log entries: %v", log.Entries)
+	}
 }
@@ -8,6 +8,13 @@ import (
 type Scanner struct {
 	full    string
 	pos     int
+	start   int
+	end     int
+	lines   []int
+	src     *Source
+	rev     bool
+	limits  *limiter
+	rules   map[*Rule]*Rule
 	Tracker Tracker
 }
 
@@ -16,6 +23,7 @@ func NewScanner(str string) *Scanner {
 	return &Scanner{
 		full: str,
 		pos:  0,
+		end:  len(str),
 	}
 }
 
@@ -102,6 +110,9 @@ func (s *Scanner) To(str string) bool {
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) IfFold(str string) bool {
 	i := len(str)
+	if len(s.Tail()) < i {
+		return false
+	}
 	prefix := s.Tail()[:i]
 	if strings.EqualFold(prefix, str) {
 		return s.Move(i)
@@ -128,7 +139,7 @@ func (s *Scanner) ToFold(str string) bool {
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) IfRune(r rune) bool {
 	first, i := utf8.DecodeRuneInString(s.Tail())
-	if i == -1 || first != r {
+	if i == 0 || first != r {
 		return false
 	}
 	return s.Move(i)
@@ -297,36 +308,34 @@ func (s *Scanner) WhileMatch(check MatchFunc) bool {
 // ----------------------------------------------------------------------- state
 // Returns the right side from the current position in s.
 func (s *Scanner) Tail() string {
-	return s.full[s.pos:]
+	return s.full[s.pos:s.end]
 }
 
 // Returns the left side from the current position in s.
 func (s *Scanner) Head() string {
-	return s.full[:s.pos]
+	return s.full[s.start:s.pos]
 }
 
 // Returns the current line and column in the full string.
+// The column counts bytes, each tab counts as tab bytes.
 func (s *Scanner) LineCol(tab int) (int, int) {
-	lines := strings.Split(strings.ReplaceAll(s.Head(), "\r\n", "\n"), "\n")
-	if len(lines) == 0 {
-		return 0, 0
-	}
-	last := lines[len(lines)-1]
+	line := s.lineOf(s.pos)
+	last := s.full[s.lineStarts()[line]:s.pos]
 	tabs := strings.Count(last, "\t")
 	n := (len(last) - tabs) + tabs*tab
-	return len(lines), n + 1
+	return line + 1, n + 1
 }
 
 // A positive value moves s n bytes to the right, a negative value moves s n bytes to the left.
+// Fails after a limit of s was exceeded or the context of UseContext was done.
 func (s *Scanner) Move(n int) bool {
 	npos := s.pos + n
-	if 0 > npos || npos > len(s.full) {
+	if s.start > npos || npos > s.end || s.halted() {
 		return false
 	}
+	from := s.pos
 	s.pos = npos
-	if s.Tracker != nil {
-		s.Tracker.Update(s.Mark())
-	}
+	s.track(from)
 	return true
 }
 
@@ -356,12 +365,12 @@ func (s *Scanner) MoveRunes(n int) bool {
 
 // Returns true if s is at the end, otherwise false.
 func (s *Scanner) AtEnd() bool {
-	return len(s.full) == s.pos
+	return s.end == s.pos
 }
 
 // Returns true if s is at the start, otherwise false.
 func (s *Scanner) AtStart() bool {
-	return s.pos == 0
+	return s.pos == s.start
 }
 
 // ---------------------------------------------------------------------- Marker
@@ -371,24 +380,70 @@ type Marker int
 // Moves s to the marked position.
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) ToMarker(m Marker) bool {
-	if m < 0 && Marker(len(s.full)) <= m {
+	if int(m) < s.start || s.end < int(m) {
 		return false
 	}
+	from := s.pos
 	s.pos = int(m)
-	if s.Tracker != nil {
-		s.Tracker.Update(s.Mark())
-	}
+	s.track(from)
 	return true
 }
 
 // Moves s to the end of the text that should be scanned.
 func (s *Scanner) ToEnd() bool {
-	return s.ToMarker(Marker(len(s.full)))
+	return s.ToMarker(Marker(s.end))
 }
 
 // Moves s to the start of the text that should be scanned.
 func (s *Scanner) ToStart() bool {
-	return s.ToMarker(Marker(0))
+	return s.ToMarker(Marker(s.start))
+}
+
+// Window creates a Scanner that reads from the same text as s, but only the sub string that t marks.
+// The Scanner uses the absolute Marker values, the Tracker, the Source, the limits and the context of s.
+// The Scanner starts at the begin of t or at the end of t if s reads in reverse direction.
+func (s *Scanner) Window(t Token) *Scanner {
+	from, to := int(t.from), int(t.to)
+	if from < s.start {
+		from = s.start
+	}
+	if to > s.end {
+		to = s.end
+	}
+	if from > to {
+		from = to
+	}
+	sub := &Scanner{
+		full:    s.full,
+		pos:     from,
+		start:   from,
+		end:     to,
+		src:     s.src,
+		rev:     s.rev,
+		limits:  s.limits,
+		rules:   s.rules,
+		Tracker: s.Tracker,
+	}
+	if s.rev {
+		sub.pos = to
+	}
+	return sub
+}
+
+// track informs the limiter and the Tracker about the move from the position from.
+// In reverse direction only a RevTracker will be informed.
+func (s *Scanner) track(from int) {
+	if s.limits != nil {
+		s.limits.moved(s, from)
+	}
+	if s.Tracker == nil {
+		return
+	}
+	if !s.rev {
+		s.Tracker.Update(s.Mark())
+	} else if rt, ok := s.Tracker.(RevTracker); ok {
+		rt.RevUpdate(s.Mark())
+	}
 }
 
 // Returns a Marker to mark the current positon in the text.
@@ -13,6 +13,33 @@ func NewRevScanner(str string) *Scanner {
 	return sca
 }
 
+// Reversed reports if the Reader that read from s currently read in reverse direction.
+func (s *Scanner) Reversed() bool {
+	return s.rev
+}
+
+// RevUse uses r in reverse direction on the scanner.
+// The scanner is only moved if no error occurs.
+func (s *Scanner) RevUse(r Reader) error {
+	return s.Use(Rev(r))
+}
+
+// moveOn moves s n runes in the current reading direction.
+func (s *Scanner) moveOn(n int) bool {
+	if s.rev {
+		return s.MoveRunes(-n)
+	}
+	return s.MoveRunes(n)
+}
+
+// atLimit returns true if s is at the end of the current reading direction.
+func (s *Scanner) atLimit() bool {
+	if s.rev {
+		return s.AtStart()
+	}
+	return s.AtEnd()
+}
+
 // ---------------------------------------------------------------------- string
 // Moves s the length of str backward if Head() has str as the prefix.
 // Returns true if s was moved, otherwise false.
@@ -53,6 +80,9 @@ func (s *Scanner) RevTo(str string) bool {
 func (s *Scanner) RevIfFold(str string) bool {
 	i := len(str)
 	head := s.Head()
+	if len(head) < i {
+		return false
+	}
 	suffix := head[len(head)-i:]
 	if strings.EqualFold(suffix, str) {
 		return s.Move(-i)
@@ -80,7 +110,7 @@ func (s *Scanner) RevToFold(str string) bool {
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) RevIfRune(r rune) bool {
 	last, i := utf8.DecodeLastRuneInString(s.Head())
-	if i == -1 || last != r {
+	if i == 0 || last != r {
 		return false
 	}
 	return s.Move(-i)
@@ -125,7 +155,7 @@ func (s *Scanner) RevWhileRune(r rune) bool {
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) RevIfAnyRune(str string) bool {
 	last, i := utf8.DecodeLastRuneInString(s.Head())
-	if i != -1 && strings.ContainsRune(str, last) {
+	if i != 0 && strings.ContainsRune(str, last) {
 		return s.Move(-i)
 	}
 	return false
@@ -168,7 +198,7 @@ func (s *Scanner) RevWhileAnyRune(str string) bool {
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) RevIfBetween(min, max rune) bool {
 	last, i := utf8.DecodeLastRuneInString(s.Head())
-	if i == -1 || inRange(min, last, max) {
+	if i == 0 || !inRange(min, last, max) {
 		return false
 	}
 	return s.Move(-i)
@@ -211,7 +241,7 @@ func (s *Scanner) RevWhileBetween(min, max rune) bool {
 // Returns true if s was moved, otherwise false.
 func (s *Scanner) RevIfMatch(check MatchFunc) bool {
 	last, i := utf8.DecodeLastRuneInString(s.Head())
-	if i == -1 || check(last) {
+	if i == 0 || !check(last) {
 		return false
 	}
 	return s.Move(-i)
@@ -1,6 +1,9 @@
 package tok
 
-import "testing"
+import (
+	"testing"
+	"unicode"
+)
 
 // ---------------------------------------------------------------------- string
 func TestRevIf(t *testing.T) {
@@ -162,10 +165,14 @@ func TestRevWhileAnyRune(t *testing.T) {
 
 // --------------------------------------------------------------------- between
 func TestRevIfBetween(t *testing.T) {
-	cases := []betweenCase{}
+	cases := []betweenCase{
+		{"abc1", '0', '9', headTail{"abc", "1"}},
+		{"abc1", 'a', 'z', headTail{"abc1", ""}},
+		{"", 'a', 'z', headTail{"", ""}},
+	}
 	for i, c := range cases {
 		sca := NewRevScanner(c.inp)
-		sca.IfBetween(c.min, c.max)
+		sca.RevIfBetween(c.min, c.max)
 		if e := c.exp.check(i, sca); e != nil {
 			t.Errorf("%v", e)
 		}
@@ -196,7 +203,10 @@ func TestRevWhileBetween(t *testing.T) {
 
 // ---------------------------------------------------------------------- match
 func TestRevIfMatch(t *testing.T) {
-	cases := []matchCase{}
+	cases := []matchCase{
+		{"abc1", unicode.IsDigit, headTail{"abc", "1"}},
+		{"abc1", unicode.IsLetter, headTail{"abc1", ""}},
+	}
 	for i, c := range cases {
 		sca := NewRevScanner(c.inp)
 		sca.RevIfMatch(c.f)
@@ -373,3 +373,23 @@ func TestMoveRunes(t *testing.T) {
 	checkTrueMove(sca, -2, "a@", "ä!")
 	checkTrueMove(sca, -2, "", "a@ä!")
 }
+
+func TestWindow(t *testing.T) {
+	sca := NewScanner("abc def ghi")
+	win := sca.Window(MakeToken(4, 7))
+	if win.Mark() != 4 || win.Tail() != "def" || win.Head() != "" {
+		t.Errorf("unexpected window: %d %q %q", win.Mark(), win.Head(), win.Tail())
+	}
+	if !win.If("de") || win.Head() != "de" || win.Mark() != 6 {
+		t.Errorf("unexpected move: %d %q", win.Mark(), win.Head())
+	}
+	if win.Move(2) || win.ToMarker(8) || win.ToMarker(3) {
+		t.Errorf("moved outside of the window")
+	}
+	if !win.ToEnd() || win.Mark() != 7 || !win.AtEnd() {
+		t.Errorf("unexpected end: %d", win.Mark())
+	}
+	if !win.ToStart() || win.Mark() != 4 || !win.AtStart() {
+		t.Errorf("unexpected start: %d", win.Mark())
+	}
+}
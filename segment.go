@@ -108,6 +108,6 @@ func (s Segment) Segmentate(segments []Segment) ([]Segment, error) {
 
 // Segmentate splits the full string of a Scanner into segments.
 func (s *Scanner) Segmentate(segments []Segment) ([]Segment, error) {
-	rest := Segment{"", MakeToken(0, Marker(len(s.full)))}
+	rest := Segment{"", MakeToken(Marker(s.start), Marker(s.end))}
 	return rest.Segmentate(segments)
 }
@@ -0,0 +1,201 @@
+package tok
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// File is a named text that can be part of a Source.
+type File struct {
+	Name    string
+	Content string
+}
+
+type sourceSpan struct {
+	start  int
+	file   int
+	offset int
+	len    int
+}
+
+// Source combines the content of Files to a text that a Scanner can read.
+// Each Marker of the text can be mapped back to the File and the Position in the File.
+// The Source should be complete before a Scanner reads from it.
+type Source struct {
+	files []File
+	lines []*Scanner
+	spans []sourceSpan
+	text  string
+}
+
+// NewSource creates a Source that concatenates the content of files.
+func NewSource(files ...File) *Source {
+	src := &Source{}
+	for _, f := range files {
+		src.Append(f)
+	}
+	return src
+}
+
+// NewFileSource creates a Source with a single file.
+func NewFileSource(name, content string) *Source {
+	return NewSource(File{name, content})
+}
+
+func (src *Source) addFile(f File) int {
+	src.files = append(src.files, f)
+	src.lines = append(src.lines, NewScanner(f.Content))
+	return len(src.files) - 1
+}
+
+// Append appends the content of f at the end of the text.
+// Returns the Marker where the content of f starts in the text.
+func (src *Source) Append(f File) Marker {
+	start := len(src.text)
+	i := src.addFile(f)
+	if f.Content != "" {
+		src.spans = append(src.spans, sourceSpan{start, i, 0, len(f.Content)})
+	}
+	src.text += f.Content
+	return Marker(start)
+}
+
+// Include inserts the content of f at the Marker m of the text.
+// All text after m will be moved behind the included content.
+func (src *Source) Include(m Marker, f File) error {
+	at := int(m)
+	if at < 0 || at > len(src.text) {
+		return fmt.Errorf("invalid include position %d", at)
+	}
+	i := src.addFile(f)
+	if f.Content == "" {
+		return nil
+	}
+	inc := sourceSpan{at, i, 0, len(f.Content)}
+	spans := []sourceSpan{}
+	for _, sp := range src.spans {
+		end := sp.start + sp.len
+		switch {
+		case end <= at:
+			spans = append(spans, sp)
+		case sp.start >= at:
+			sp.start += inc.len
+			spans = append(spans, sp)
+		default:
+			n := at - sp.start
+			spans = append(spans, sourceSpan{sp.start, sp.file, sp.offset, n})
+			spans = append(spans, sourceSpan{at + inc.len, sp.file, sp.offset + n, sp.len - n})
+		}
+	}
+	spans = append(spans, inc)
+	sort.Slice(spans, func(a, b int) bool { return spans[a].start < spans[b].start })
+	src.spans = spans
+	src.text = src.text[:at] + f.Content + src.text[at:]
+	return nil
+}
+
+// Text returns the combined text of the Source.
+func (src *Source) Text() string {
+	return src.text
+}
+
+// Files returns the files of the Source.
+func (src *Source) Files() []File {
+	return src.files
+}
+
+func (src *Source) span(m Marker) (sourceSpan, bool) {
+	at := int(m)
+	i := sort.Search(len(src.spans), func(i int) bool { return src.spans[i].start > at }) - 1
+	if i < 0 {
+		return sourceSpan{}, false
+	}
+	sp := src.spans[i]
+	return sp, at <= sp.start+sp.len
+}
+
+// Locate maps the Marker m of the text back to a File and the Marker in the File.
+// Returns false if the Source has no File that contains m.
+func (src *Source) Locate(m Marker) (File, Marker, bool) {
+	sp, ok := src.span(m)
+	if !ok {
+		return File{}, Marker(0), false
+	}
+	return src.files[sp.file], Marker(sp.offset + int(m) - sp.start), true
+}
+
+// Position returns the Position in the File that contains m with a column in the unit u.
+func (src *Source) Position(m Marker, u Unit) Position {
+	sp, ok := src.span(m)
+	if !ok {
+		return Position{}
+	}
+	p := src.lines[sp.file].Position(Marker(sp.offset+int(m)-sp.start), u)
+	p.File = src.files[sp.file].Name
+	return p
+}
+
+// WrapError wraps err in a SourceError with the Position of the ReadError in err, the column is counted in Runes.
+// Returns err unchanged if it contains no ReadError or src has no File that contains the Marker of the error.
+func (src *Source) WrapError(err error) error {
+	var re ReadError
+	if !errors.As(err, &re) {
+		return err
+	}
+	p := src.Position(re.Marker, Runes)
+	if !p.IsValid() {
+		return err
+	}
+	return SourceError{err, p}
+}
+
+// LocateSegment maps seg back to the File that contains it and returns seg with the Markers of the File.
+// Returns false if seg does not lie completely in one File.
+func (src *Source) LocateSegment(seg Segment) (File, Segment, bool) {
+	sp, ok := src.span(seg.From())
+	if !ok || int(seg.To()) > sp.start+sp.len {
+		return File{}, Segment{}, false
+	}
+	delta := Marker(sp.offset - sp.start)
+	return src.files[sp.file], Segment{seg.Info, MakeToken(seg.From()+delta, seg.To()+delta)}, true
+}
+
+// SegmentPositions returns the Positions where seg starts and ends in its File with a column in the unit u.
+// The Positions are invalid if seg does not lie completely in one File.
+func (src *Source) SegmentPositions(seg Segment, u Unit) (Position, Position) {
+	sp, ok := src.span(seg.From())
+	if !ok || int(seg.To()) > sp.start+sp.len {
+		return Position{}, Position{}
+	}
+	delta := Marker(sp.offset - sp.start)
+	from, to := src.lines[sp.file].Positions(MakeToken(seg.From()+delta, seg.To()+delta), u)
+	from.File = src.files[sp.file].Name
+	to.File = from.File
+	return from, to
+}
+
+// String returns the names of the files in the Source.
+func (src *Source) String() string {
+	names := []string{}
+	for _, f := range src.files {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ";")
+}
+
+//------------------------------------------------------------------------------
+
+// NewSourceScanner creates a new Scanner to scan the text of src.
+// Positions of the Scanner refer to the files in src, Source.WrapError maps the ReadErrors of the Scanner.
+func NewSourceScanner(src *Source) *Scanner {
+	s := NewScanner(src.Text())
+	s.src = src
+	return s
+}
+
+// Source returns the Source of s, the value is nil if s was not created from a Source.
+func (s *Scanner) Source() *Source {
+	return s.src
+}
@@ -0,0 +1,103 @@
+package tok
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	src := NewSource(File{"a.lua", "local a = 1\nreturn a\n"}, File{"b.lua", "x = 2\n"})
+	if src.Text() != "local a = 1\nreturn a\nx = 2\n" {
+		t.Errorf("unexpected text: %q", src.Text())
+	}
+	cases := []struct {
+		m   Marker
+		exp string
+	}{
+		{0, "a.lua:1:1"},
+		{19, "a.lua:2:8"},
+		{21, "b.lua:1:1"},
+		{25, "b.lua:1:5"},
+	}
+	for i, c := range cases {
+		if p := src.Position(c.m, Runes); p.String() != c.exp {
+			t.Errorf("%d unexpected position: %s != %s", i, p, c.exp)
+		}
+	}
+
+	err := src.Include(Marker(12), File{"inc.lua", "y = 3\n"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if src.Text() != "local a = 1\ny = 3\nreturn a\nx = 2\n" {
+		t.Errorf("unexpected text: %q", src.Text())
+	}
+	cases = []struct {
+		m   Marker
+		exp string
+	}{
+		{11, "a.lua:1:12"},
+		{12, "inc.lua:1:1"},
+		{18, "a.lua:2:1"},
+		{25, "a.lua:2:8"},
+		{31, "b.lua:1:5"},
+	}
+	for i, c := range cases {
+		if p := src.Position(c.m, Runes); p.String() != c.exp {
+			t.Errorf("%d unexpected position: %s != %s", i, p, c.exp)
+		}
+	}
+	f, m, ok := src.Locate(Marker(20))
+	if !ok || f.Name != "a.lua" || m != Marker(14) {
+		t.Errorf("unexpected location: %s %d", f.Name, m)
+	}
+
+	sca := NewSourceScanner(src)
+	sca.Move(18)
+	err = sca.Use(Lit("local"))
+	if err == nil || err.Error() != `not able to read "local" at 18` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = src.WrapError(err)
+	if err == nil || err.Error() != `not able to read "local" at a.lua:2:1` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var re ReadError
+	if !errors.As(err, &re) || re.Marker != Marker(18) {
+		t.Errorf("expected the ReadError in: %v", err)
+	}
+
+	err = src.WrapError(ReadError{Marker(31), "x"})
+	if err.Error() != "not able to read x at b.lua:1:5" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = src.WrapError(fmt.Errorf("lua parse error: %w", ReadError{Marker(31), "x"}))
+	if err.Error() != "lua parse error: not able to read x at b.lua:1:5" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := src.WrapError(LimitError{Marker(31), "depth", 1}); err.Error() != "exceeded depth limit of 1 at 31" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// "return" in a.lua after the included file
+	f, seg, ok := src.LocateSegment(Segment{"word", MakeToken(18, 24)})
+	if !ok || f.Name != "a.lua" || seg.String() != "word[12-18)" {
+		t.Errorf("unexpected location: %s %s", f.Name, seg)
+	}
+	from, to := src.SegmentPositions(Segment{"word", MakeToken(18, 24)}, Runes)
+	if from.String() != "a.lua:2:1" || to.String() != "a.lua:2:7" {
+		t.Errorf("unexpected positions: %s %s", from, to)
+	}
+	// "y = 3\n" ends where a.lua continues
+	from, to = src.SegmentPositions(Segment{"inc", MakeToken(12, 18)}, Runes)
+	if from.String() != "inc.lua:1:1" || to.String() != "inc.lua:2:1" {
+		t.Errorf("unexpected positions: %s %s", from, to)
+	}
+	if _, _, ok := src.LocateSegment(Segment{"both", MakeToken(10, 14)}); ok {
+		t.Errorf("a segment over two files should not be located")
+	}
+	if from, _ := src.SegmentPositions(Segment{"both", MakeToken(10, 14)}, Runes); from.IsValid() {
+		t.Errorf("unexpected position: %s", from)
+	}
+}
@@ -0,0 +1,331 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aiq/tok"
+)
+
+func asReader(i interface{}) (Reader, bool) {
+	if str, ok := i.(string); ok {
+		return Lit(str), true
+	} else if r, ok := i.(Reader); ok {
+		return r, true
+	} else {
+		return nil, false
+	}
+}
+
+// Reader can be used to read from a Stream.
+type Reader interface {
+	Read(s *Stream) error
+	What() string
+}
+
+// ------------------------------------------------------------------------------
+type invalid struct {
+	err error
+}
+
+func (r invalid) Read(s *Stream) error {
+	return fmt.Errorf("INVALID-READER: %v", r.err)
+}
+
+func (r invalid) What() string {
+	return fmt.Sprintf("%s{%v}", tok.InvalidReaderMarker, r.err)
+}
+
+// invalidReader is a Reader that allways fails.
+// The arguments will be passed to fmt.Errorf.
+func invalidReader(format string, a ...interface{}) Reader {
+	return invalid{fmt.Errorf(format, a...)}
+}
+
+// ------------------------------------------------------------------------------
+type anyReader struct {
+	readers []Reader
+}
+
+func (r *anyReader) Read(s *Stream) error {
+	m := s.Mark()
+	var deepest tok.ReadError
+	for _, sub := range r.readers {
+		e := sub.Read(s)
+		if e == nil {
+			return nil
+		}
+		if re, ok := e.(tok.ReadError); ok && re.Later(deepest) {
+			deepest = re
+		}
+	}
+	s.ToMarker(m)
+	return deepest
+}
+
+func (r *anyReader) What() string {
+	sub := []string{}
+	for _, sr := range r.readers {
+		sub = append(sub, sr.What())
+	}
+	return "[ " + strings.Join(sub, " ") + " ]"
+}
+
+// Any creates a Reader that tries to Read with any of the given Reader.
+// The type of the list values can be string or Reader.
+// The first Reader that reads without an error will be used.
+func Any(list ...interface{}) Reader {
+	readers := []Reader{}
+	for i, ai := range list {
+		r, ok := asReader(ai)
+		if !ok {
+			return invalidReader("invalid Any parameter at %d: unknown type %T", i+1, ai)
+		}
+		readers = append(readers, r)
+	}
+	return &anyReader{readers}
+}
+
+// ------------------------------------------------------------------------------
+type atReader struct {
+	sub Reader
+}
+
+func (r *atReader) Read(s *Stream) error {
+	m := s.Mark()
+	err := r.sub.Read(s)
+	s.ToMarker(m)
+	return err
+}
+
+func (r *atReader) What() string {
+	return "@" + r.sub.What()
+}
+
+// At creates a Reader that checks the current postion of the stream.
+// The Reader does not move the stream.
+func At(r Reader) Reader {
+	return &atReader{r}
+}
+
+// ------------------------------------------------------------------------------
+type atEndReader struct {
+}
+
+func (r atEndReader) Read(s *Stream) error {
+	return s.ErrorIfFalse(s.AtEnd(), r.What())
+}
+
+func (r atEndReader) What() string {
+	return "@END"
+}
+
+// AtEnd creates a Reader that checks the stream reaches the end.
+func AtEnd() Reader {
+	return atEndReader{}
+}
+
+// ------------------------------------------------------------------------------
+type kindReader struct {
+	kind string
+}
+
+func (r kindReader) Read(s *Stream) error {
+	seg, ok := s.Peek()
+	if !ok || seg.Info != r.kind {
+		return s.ErrorFor(r.What())
+	}
+	s.Move(1)
+	return nil
+}
+
+func (r kindReader) What() string {
+	return "<" + r.kind + ">"
+}
+
+// Kind creates a Reader that reads a token with the name kind.
+func Kind(kind string) Reader {
+	return kindReader{kind}
+}
+
+// ------------------------------------------------------------------------------
+type litReader struct {
+	str string
+}
+
+func (r litReader) Read(s *Stream) error {
+	seg, ok := s.Peek()
+	if !ok || s.Text(seg) != r.str {
+		return s.ErrorFor(r.What())
+	}
+	s.Move(1)
+	return nil
+}
+
+func (r litReader) What() string {
+	return strconv.QuoteToGraphic(r.str)
+}
+
+// Lit creates a Reader that reads a token with the text str.
+func Lit(str string) Reader {
+	return litReader{str}
+}
+
+// ------------------------------------------------------------------------------
+type manyReader struct {
+	sub Reader
+}
+
+func (r manyReader) Read(s *Stream) error {
+	start := s.Mark()
+	for r.sub.Read(s) == nil {
+	}
+	end := s.Mark()
+	return s.ErrorIfFalse(start < end, r.What())
+}
+
+func (r manyReader) What() string {
+	return "+" + r.sub.What()
+}
+
+// Many creates a Reader that expects that i matches one or more times.
+// The type of i can be string or Reader.
+func Many(i interface{}) Reader {
+	r, ok := asReader(i)
+	if !ok {
+		return invalidReader("invalid Many parameter: unknown type %T", i)
+	}
+	return &manyReader{r}
+}
+
+// ------------------------------------------------------------------------------
+type notReader struct {
+	sub Reader
+}
+
+func (r *notReader) Read(s *Stream) error {
+	m := s.Mark()
+	err := r.sub.Read(s)
+	s.ToMarker(m)
+	if err == nil || s.AtEnd() {
+		return s.ErrorFor(r.What())
+	}
+	s.Move(1)
+	return nil
+}
+
+func (r *notReader) What() string {
+	return "!" + r.sub.What()
+}
+
+// Not creates a Reader that moves 1 token forward if r does not match.
+func Not(r Reader) Reader {
+	return &notReader{r}
+}
+
+// ------------------------------------------------------------------------------
+type optReader struct {
+	sub Reader
+}
+
+func (r *optReader) Read(s *Stream) error {
+	r.sub.Read(s)
+	return nil
+}
+
+func (r *optReader) What() string {
+	return "?" + r.sub.What()
+}
+
+// Opt creates Reader that catches the error that i can produce and returns allways nil.
+// The type of i can be string or Reader.
+func Opt(i interface{}) Reader {
+	r, ok := asReader(i)
+	if !ok {
+		return invalidReader("invalid Opt parameter: unknown type %T", i)
+	}
+	return &optReader{r}
+}
+
+// ------------------------------------------------------------------------------
+type seqReader struct {
+	readers []Reader
+}
+
+func (r *seqReader) Read(s *Stream) error {
+	m := s.Mark()
+	for _, sub := range r.readers {
+		if e := sub.Read(s); e != nil {
+			s.ToMarker(m)
+			return e
+		}
+	}
+	return nil
+}
+
+func (r *seqReader) What() string {
+	sub := []string{}
+	for _, sr := range r.readers {
+		sub = append(sub, sr.What())
+	}
+	return strings.Join(sub, " ")
+}
+
+// Seq creates a Reader that tries to Read with all readers in list sequential.
+// The type of the list values can be string or Reader.
+func Seq(list ...interface{}) Reader {
+	readers := []Reader{}
+	for i, ai := range list {
+		r, ok := asReader(ai)
+		if !ok {
+			return invalidReader("invalid Seq parameter at %d: unknown type %T", i+1, ai)
+		}
+		readers = append(readers, r)
+	}
+	return &seqReader{readers}
+}
+
+// ------------------------------------------------------------------------------
+type wrapReader struct {
+	what string
+	f    func(*Stream) error
+}
+
+func (r wrapReader) Read(s *Stream) error {
+	return r.f(s)
+}
+
+func (r wrapReader) What() string {
+	return r.what
+}
+
+// Wrap creates a Reader that wraps f.
+func Wrap(what string, f func(*Stream) error) Reader {
+	return wrapReader{what, f}
+}
+
+// ------------------------------------------------------------------------------
+type zomReader struct {
+	sub Reader
+}
+
+func (r zomReader) Read(s *Stream) error {
+	for r.sub.Read(s) == nil {
+	}
+	return nil
+}
+
+func (r zomReader) What() string {
+	return "*" + r.sub.What()
+}
+
+// Zom creates a Reader that expects that i matches zero or more times.
+// The type of i can be string or Reader.
+func Zom(i interface{}) Reader {
+	r, ok := asReader(i)
+	if !ok {
+		return invalidReader("invalid Zom parameter: unknown type %T", i)
+	}
+	return &zomReader{r}
+}
@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"fmt"
+
+	"github.com/aiq/tok"
+)
+
+// Rule can be used to set the rules of a grammar that reads from a Stream.
+type Rule struct {
+	Name   string
+	Reader Reader
+}
+
+// Pick collects the Segments if a Reader was moven and sets the Info field with the Rule Name.
+// The Segments refer to the text of the Scanner.
+func (r *Rule) Pick(basket *tok.Basket) {
+	r.Reader = Pick(r.Reader, basket, r.Name)
+}
+
+func (r *Rule) Read(s *Stream) error {
+	return r.Reader.Read(s)
+}
+
+func (r *Rule) What() string {
+	return r.Name
+}
+
+func (r *Rule) Rule() string {
+	return fmt.Sprintf("%s: %s", r.Name, r.Reader.What())
+}
+
+// ------------------------------------------------------------------------------
+type pickReader struct {
+	info   string
+	basket *tok.Basket
+	sub    Reader
+}
+
+func (r *pickReader) Read(s *Stream) error {
+	t, err := s.TokenizeUse(r.sub)
+	if err == nil {
+		r.basket.Add(tok.Segment{
+			Info:  r.info,
+			Token: t,
+		})
+	}
+	return err
+}
+
+func (r *pickReader) What() string {
+	return r.sub.What()
+}
+
+// Pick creates a Reader that appends the Segments that r reads forward to the Basket with info as Info value.
+func Pick(r Reader, b *tok.Basket, info string) Reader {
+	return &pickReader{
+		info:   info,
+		basket: b,
+		sub:    r,
+	}
+}
@@ -0,0 +1,156 @@
+package stream
+
+import (
+	"github.com/aiq/tok"
+)
+
+// Marker represents a position in the token stream.
+type Marker int
+
+// Stream is a cursor over the tokens that a tok.Lexer produced.
+type Stream struct {
+	sca     *tok.Scanner
+	tokens  []tok.Segment
+	pos     int
+	Tracker tok.Tracker
+}
+
+// New creates a Stream over tokens that were read from sca.
+func New(sca *tok.Scanner, tokens []tok.Segment) *Stream {
+	return &Stream{
+		sca:    sca,
+		tokens: tokens,
+	}
+}
+
+// Lex creates a Stream with the tokens that l reads from sca.
+func Lex(l *tok.Lexer, sca *tok.Scanner) (*Stream, error) {
+	tokens, err := l.Tokenize(sca)
+	if err != nil {
+		return nil, err
+	}
+	return New(sca, tokens), nil
+}
+
+// Returns a new empty Basket that is coupled as Tracker on the stream.
+func (s *Stream) NewBasket() *tok.Basket {
+	b := &tok.Basket{}
+	s.Tracker = b
+	return b
+}
+
+// ----------------------------------------------------------------------- state
+
+// Tokens returns all tokens of the stream.
+func (s *Stream) Tokens() []tok.Segment {
+	return s.tokens
+}
+
+// Scanner returns the Scanner that the tokens refer to.
+func (s *Stream) Scanner() *tok.Scanner {
+	return s.sca
+}
+
+// Peek returns the current token without moving s.
+// Returns false if s is at the end.
+func (s *Stream) Peek() (tok.Segment, bool) {
+	if s.AtEnd() {
+		return tok.Segment{}, false
+	}
+	return s.tokens[s.pos], true
+}
+
+// Text returns the text of the token seg.
+func (s *Stream) Text(seg tok.Segment) string {
+	return s.sca.Get(seg.Token)
+}
+
+// Returns true if s is at the end, otherwise false.
+func (s *Stream) AtEnd() bool {
+	return s.pos == len(s.tokens)
+}
+
+// Returns true if s is at the start, otherwise false.
+func (s *Stream) AtStart() bool {
+	return s.pos == 0
+}
+
+// Move moves s n tokens, a negative value moves s backward.
+func (s *Stream) Move(n int) bool {
+	return s.ToMarker(Marker(s.pos + n))
+}
+
+// Returns a Marker to mark the current position in the stream.
+func (s *Stream) Mark() Marker {
+	return Marker(s.pos)
+}
+
+// Moves s to the marked position.
+// Returns true if s was moved, otherwise false.
+func (s *Stream) ToMarker(m Marker) bool {
+	if m < 0 || int(m) > len(s.tokens) {
+		return false
+	}
+	s.pos = int(m)
+	if s.Tracker != nil {
+		s.Tracker.Update(s.TextMarker(m))
+	}
+	return true
+}
+
+// TextMarker returns the Marker in the text of the Scanner where the token at m starts.
+func (s *Stream) TextMarker(m Marker) tok.Marker {
+	if len(s.tokens) == 0 {
+		return s.sca.Mark()
+	}
+	if int(m) < len(s.tokens) {
+		return s.tokens[m].From()
+	}
+	return s.tokens[len(s.tokens)-1].To()
+}
+
+// Span returns the Token in the text of the Scanner that covers the tokens between a and b.
+func (s *Stream) Span(a, b Marker) tok.Token {
+	if a > b {
+		a, b = b, a
+	}
+	if a == b {
+		m := s.TextMarker(a)
+		return tok.MakeToken(m, m)
+	}
+	return tok.MakeToken(s.tokens[a].From(), s.tokens[b-1].To())
+}
+
+// ErrorFor generates a tok.ReadError for name at the current token.
+func (s *Stream) ErrorFor(name string) error {
+	return s.sca.ErrorAt(s.TextMarker(s.Mark()), name)
+}
+
+// ErrorIfFalse generates a ErrorFor if ok is false, otherwise returns the function nil.
+func (s *Stream) ErrorIfFalse(ok bool, name string) error {
+	if !ok {
+		return s.ErrorFor(name)
+	}
+	return nil
+}
+
+// Use uses r on the stream.
+// The stream is only moved if no error occurs.
+func (s *Stream) Use(r Reader) error {
+	m := s.Mark()
+	err := r.Read(s)
+	if err != nil {
+		s.ToMarker(m)
+	}
+	return err
+}
+
+// TokenizeUse marks the text that was read by r.
+func (s *Stream) TokenizeUse(r Reader) (tok.Token, error) {
+	a := s.Mark()
+	err := r.Read(s)
+	if err != nil {
+		s.ToMarker(a)
+	}
+	return s.Span(a, s.Mark()), err
+}
@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/aiq/tok"
+)
+
+func newLexer() *tok.Lexer {
+	return tok.NewLexer(
+		tok.LexSkip("ws", tok.Many(tok.WS())),
+		tok.LexSkip("comment", tok.Seq("--", tok.To(tok.Any(tok.NL(), tok.AtEnd())))),
+		tok.Lex("name", tok.Seq(tok.BetweenAny("a-zA-Z"), tok.Zom(tok.BetweenAny("a-zA-Z0-9")))),
+		tok.Lex("number", tok.Many(tok.Digit())),
+		tok.Lex("op", tok.Any("==", "=", "+", ",")),
+	)
+}
+
+func TestStream(t *testing.T) {
+	sca := tok.NewScanner("local a, b = 1 + 2 -- comment\nlocal c = 3")
+	s, err := Lex(newLexer(), sca)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.Tokens()) != 12 {
+		t.Errorf("unexpected number of tokens: %d", len(s.Tokens()))
+	}
+
+	basket := s.NewBasket()
+	exp := Rule{Name: "exp"}
+	exp.Reader = Seq(Kind("number"), Zom(Seq("+", Kind("number"))))
+	names := Rule{Name: "names", Reader: Seq(Kind("name"), Zom(Seq(",", Kind("name"))))}
+	stat := Rule{Name: "stat", Reader: Seq("local", &names, Opt(Seq("=", &exp)))}
+	exp.Pick(basket)
+	names.Pick(basket)
+	err = s.Use(Seq(Many(&stat), AtEnd()))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if basket.String() != "names[6-10);exp[13-18);names[36-37);exp[40-41)" {
+		t.Errorf("unexpected picked values: %s", basket.String())
+	}
+
+	s.ToMarker(0)
+	err = s.Use(Seq("local", Kind("number")))
+	re, ok := err.(tok.ReadError)
+	if !ok || re.Marker != 6 || re.What != "<number>" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -29,6 +29,16 @@ func (t Token) Before(oth Token) bool {
 	return t.to <= oth.from
 }
 
+// From returns the Marker where t starts.
+func (t Token) From() Marker {
+	return t.from
+}
+
+// To returns the Marker where t ends.
+func (t Token) To() Marker {
+	return t.to
+}
+
 func (t Token) Len() int {
 	return int(t.to - t.from)
 }
@@ -84,7 +94,7 @@ func (s *Scanner) Tokenize(f ScopeFunc) (Token, bool) {
 // TokenizeUse marks the sub string that was read by r.
 func (s *Scanner) TokenizeUse(r Reader) (Token, error) {
 	a := s.Mark()
-	err := r.Read(s)
+	err := s.read(r.Read)
 	if err != nil {
 		s.ToMarker(a)
 	}
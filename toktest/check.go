@@ -0,0 +1,48 @@
+// Package toktest provides helpers to test Grammars.
+package toktest
+
+import (
+	"fmt"
+
+	"github.com/aiq/tok"
+)
+
+// Check reads text with g and returns an error if one of the invariants of a Grammar is broken:
+// the read doesn't panic, the Scanner is restored if the read fails, the picked Segments are within the text
+// and don't clash, and each picked Segment of a successful read can be appended to a Graph.
+// Check picks the Segments with all Rules of g, g should therefore be a new Grammar.
+func Check(g tok.Grammar, text string) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%s panics for %q: %v", g.What(), text, v)
+		}
+	}()
+	sca := tok.NewScanner(text)
+	basket := sca.NewBasketFor(g)
+	start := sca.Mark()
+	readErr := sca.Use(g)
+	if readErr != nil && sca.Mark() != start {
+		return fmt.Errorf("%s moved the scanner to %d after the error %v for %q", g.What(), sca.Mark(), readErr, text)
+	}
+	segs := basket.Picked()
+	for i, seg := range segs {
+		if seg.From() < start || int(seg.To()) > len(text) {
+			return fmt.Errorf("%s picked %s outside of %q", g.What(), seg, text)
+		}
+		for _, oth := range segs[:i] {
+			if seg.Clashes(oth.Token) {
+				return fmt.Errorf("%s picked the clashing segments %s and %s for %q", g.What(), oth, seg, text)
+			}
+		}
+	}
+	if readErr != nil {
+		return nil
+	}
+	graph := tok.NewGraph(g.What())
+	for _, seg := range segs {
+		if _, ok := graph.Append(seg); !ok {
+			return fmt.Errorf("%s picked %s that can't be appended to the graph for %q", g.What(), seg, text)
+		}
+	}
+	return nil
+}
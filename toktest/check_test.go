@@ -0,0 +1,65 @@
+package toktest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/grammar"
+)
+
+const pairGrammar = `pair = key '=' value
+key   = +'a'..'z'
+value = +DIGIT
+`
+
+func TestCheck(t *testing.T) {
+	for _, text := range []string{"ab=12", "ab=", "=12", ""} {
+		g, err := grammar.Load("pair", pairGrammar)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := Check(g, text); err != nil {
+			t.Errorf("unexpected error for %q: %v", text, err)
+		}
+	}
+
+	g, _ := grammar.Load("pair", pairGrammar)
+	value, _ := g.Rule("value")
+	value.Reader = tok.Wrap("boom", func(s *tok.Scanner) error {
+		panic("boom")
+	})
+	err := Check(g, "ab=12")
+	if err == nil || !strings.Contains(err.Error(), "panics") {
+		t.Errorf("expected a panic error, got %v", err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "testdata/pair.mxt", func() tok.Grammar {
+		g, _ := grammar.Load("pair", pairGrammar)
+		return g
+	})
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pair.mxt")
+	text := "// input.txt --> X\n//ab=12\n//X tree -->\nwrong\n// note -->\nkept\n"
+	if err := os.WriteFile(filename, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, filename, func() tok.Grammar {
+		g, err := grammar.Load("pair", "pair = ?\"//\" +'a'..'z' '=' +DIGIT\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return g
+	}, true)
+	data, _ := os.ReadFile(filename)
+	exp := "// input.txt --> X\n//ab=12\n//X note -->\nkept\n// tree -->\ninput.txt[0-7)\n  pair[0-7) \"//ab=12\"\n"
+	if string(data) != exp {
+		t.Errorf("unexpected golden file:\n%s\nexpected:\n%s", data, exp)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build go1.18
+// +build go1.18
+
+package toktest
+
+import (
+	"testing"
+
+	"github.com/aiq/tok"
+)
+
+// Fuzz adds the seeds to the corpus of f and runs Check with a new Grammar for each input of the fuzz engine.
+func Fuzz(f *testing.F, newGrammar func() tok.Grammar, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		if err := Check(newGrammar(), text); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
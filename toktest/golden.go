@@ -0,0 +1,176 @@
+package toktest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aiq/tok"
+	"github.com/aiq/tok/mxt"
+)
+
+// goldenCase is an input chunk with the chunks that follow it.
+type goldenCase struct {
+	input    *mxt.Chunk
+	expected []*mxt.Chunk
+}
+
+func (gc *goldenCase) find(name string) *mxt.Chunk {
+	for _, c := range gc.expected {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// run reads the input with g and returns the rendered Graph or the error message.
+func (gc *goldenCase) run(g tok.Grammar) (tree string, err error) {
+	src := tok.NewFileSource(gc.input.Name, gc.input.Content)
+	sca := tok.NewSourceScanner(src)
+	basket := sca.NewBasketFor(g)
+	if err := sca.Use(g); err != nil {
+		return "", src.WrapError(err)
+	}
+	if err := sca.ErrorIfFalse(sca.AtEnd(), "end of text"); err != nil {
+		return "", src.WrapError(err)
+	}
+	b := &strings.Builder{}
+	writeTree(b, sca, tok.BuildGraph(gc.input.Name, basket.Picked()).Root, 0)
+	return b.String(), nil
+}
+
+// writeTree writes the Nodes as indented text, each leaf with the quoted text.
+// Nodes of empty Segments are left out, their nesting depends on the order of the picked Segments.
+func writeTree(b *strings.Builder, sca *tok.Scanner, n *tok.Node, level int) {
+	subs := []*tok.Node{}
+	for _, sub := range n.Nodes {
+		if sub.Len() > 0 {
+			subs = append(subs, sub)
+		}
+	}
+	fmt.Fprintf(b, "%s%s", strings.Repeat("  ", level), n.String())
+	if len(subs) == 0 {
+		fmt.Fprintf(b, " %s", strconv.Quote(sca.Get(n.Token)))
+	}
+	b.WriteString("\n")
+	for _, sub := range subs {
+		writeTree(b, sca, sub, level+1)
+	}
+}
+
+// check compares the result of the case with the expected chunks and returns the differences.
+// With update the expected chunks will be replaced by the result.
+func (gc *goldenCase) check(g tok.Grammar, update bool) []string {
+	name := "tree"
+	value, err := gc.run(g)
+	if err != nil {
+		name, value = "error", err.Error()
+	}
+	if update {
+		kept := []*mxt.Chunk{}
+		for _, c := range gc.expected {
+			if c.Name != "tree" && c.Name != "error" {
+				kept = append(kept, c)
+			}
+		}
+		gc.expected = append(kept, &mxt.Chunk{Name: name, Content: strings.TrimRight(value, "\n")})
+		return nil
+	}
+	exp := gc.find(name)
+	if exp == nil {
+		if err != nil {
+			return []string{fmt.Sprintf("unexpected error: %v", err)}
+		}
+		return []string{fmt.Sprintf("missing tree chunk for:\n%s", value)}
+	}
+	if strings.TrimRight(exp.Content, "\n") != strings.TrimRight(value, "\n") {
+		return []string{fmt.Sprintf("unexpected %s:\n%s\nexpected:\n%s", name, value, exp.Content)}
+	}
+	return nil
+}
+
+// Golden reads the cases of the MXT file and checks each with a new Grammar.
+// A case starts with a chunk whose name starts with input, the following chunks contain the expected results:
+//
+//	tree   the Graph of the picked Segments as indented text
+//	error  the error message of a failed read
+//
+// Golden rewrites the expected chunks of the file if the test binary defines a bool flag update
+// that is set, like with:
+//
+//	var update = flag.Bool("update", false, "rewrites the golden files")
+func Golden(t *testing.T, filename string, newGrammar func() tok.Grammar) {
+	t.Helper()
+	golden(t, filename, newGrammar, updating())
+}
+
+// updating returns true if the flag update is defined and set.
+func updating() bool {
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	on, _ := getter.Get().(bool)
+	return on
+}
+
+func golden(t *testing.T, filename string, newGrammar func() tok.Grammar, update bool) {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := mxt.Decode(string(data))
+	if err != nil {
+		t.Fatalf("invalid golden file %s: %v", filename, err)
+	}
+	if n := len(chunks); n > 0 {
+		chunks[n-1].Content = strings.TrimSuffix(chunks[n-1].Content, "\n")
+	}
+	head := []*mxt.Chunk{}
+	cases := []*goldenCase{}
+	for i := range chunks {
+		c := &chunks[i]
+		if strings.HasPrefix(c.Name, "input") {
+			cases = append(cases, &goldenCase{input: c})
+		} else if len(cases) == 0 {
+			head = append(head, c)
+		} else {
+			gc := cases[len(cases)-1]
+			gc.expected = append(gc.expected, c)
+		}
+	}
+	for i, gc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i+1, gc.input.Name), func(t *testing.T) {
+			for _, msg := range gc.check(newGrammar(), update) {
+				t.Error(msg)
+			}
+		})
+	}
+	if !update {
+		return
+	}
+	for _, gc := range cases {
+		head = append(head, gc.input)
+		head = append(head, gc.expected...)
+	}
+	out := []mxt.Chunk{}
+	for _, c := range head {
+		out = append(out, *c)
+	}
+	text, err := mxt.Encode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(text+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
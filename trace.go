@@ -0,0 +1,241 @@
+package tok
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TraceAxis defines the values that an exported trace uses as time.
+type TraceAxis int
+
+const (
+	// StepAxis uses the order of the enter and exit events, failed attempts stay visible.
+	StepAxis TraceAxis = iota
+	// OffsetAxis uses the byte offsets in the text.
+	// A speedscope file clamps the spans that start or end before a previous event because of backtracking.
+	OffsetAxis
+	// TimeAxis uses the wall time in nanoseconds.
+	TimeAxis
+)
+
+// traceSpan is an entry of a Log or a Node of a Graph on a TraceAxis.
+type traceSpan struct {
+	name   string
+	level  int
+	from   int64
+	to     int64
+	failed bool
+	args   map[string]interface{}
+}
+
+// spans converts the entries of l to spans on the axis.
+func (l *Log) spans(axis TraceAxis) []traceSpan {
+	res := []traceSpan{}
+	steps := l.steps()
+	for i, e := range l.Entries {
+		s := traceSpan{
+			name:   e.Info,
+			level:  e.Level,
+			failed: e.Error != nil,
+			args: map[string]interface{}{
+				"enter": e.EnterAt,
+				"exit":  e.ExitAt,
+			},
+		}
+		if e.Error != nil {
+			s.args["error"] = e.Error.Error()
+		}
+		switch axis {
+		case OffsetAxis:
+			s.from, s.to = int64(e.EnterAt), int64(e.ExitAt)
+		case TimeAxis:
+			start := l.Entries[0].EnterTime
+			s.from = e.EnterTime.Sub(start).Nanoseconds()
+			s.to = e.ExitTime.Sub(start).Nanoseconds()
+		default:
+			s.from, s.to = steps[i][0], steps[i][1]
+		}
+		if s.to < s.from {
+			s.to = s.from
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// steps returns the enter and exit step of each entry.
+func (l *Log) steps() [][2]int64 {
+	res := make([][2]int64, len(l.Entries))
+	stack := []int{}
+	step := int64(0)
+	pop := func(level int) {
+		for len(stack) > 0 && l.Entries[stack[len(stack)-1]].Level >= level {
+			res[stack[len(stack)-1]][1] = step
+			step++
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for i, e := range l.Entries {
+		pop(e.Level)
+		res[i][0] = step
+		step++
+		stack = append(stack, i)
+	}
+	pop(0)
+	return res
+}
+
+func appendNodeSpans(spans []traceSpan, n *Node, level int) []traceSpan {
+	spans = append(spans, traceSpan{
+		name:  n.Info,
+		level: level,
+		from:  int64(n.from),
+		to:    int64(n.to),
+		args:  map[string]interface{}{},
+	})
+	for _, sub := range n.Nodes {
+		spans = appendNodeSpans(spans, sub, level+1)
+	}
+	return spans
+}
+
+// ------------------------------------------------------------------------------
+type chromeEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat"`
+	Ph    string                 `json:"ph"`
+	Ts    float64                `json:"ts"`
+	Dur   float64                `json:"dur"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Cname string                 `json:"cname,omitempty"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+func writeChromeTrace(w io.Writer, spans []traceSpan, scale float64) error {
+	events := []chromeEvent{}
+	for _, s := range spans {
+		e := chromeEvent{
+			Name: s.name,
+			Cat:  "rule",
+			Ph:   "X",
+			Ts:   float64(s.from) * scale,
+			Dur:  float64(s.to-s.from) * scale,
+			Pid:  1,
+			Tid:  1,
+			Args: s.args,
+		}
+		if s.failed {
+			e.Cat = "failed"
+			e.Cname = "bad"
+		}
+		events = append(events, e)
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ns",
+	})
+}
+
+// WriteChromeTrace writes the entries of l as Chrome Trace Event JSON to w.
+// Failed attempts have the category failed.
+// The trace can be opened with chrome://tracing or https://ui.perfetto.dev.
+func (l *Log) WriteChromeTrace(w io.Writer, axis TraceAxis) error {
+	scale := 1.0
+	if axis == TimeAxis {
+		scale = 0.001
+	}
+	return writeChromeTrace(w, l.spans(axis), scale)
+}
+
+// WriteChromeTrace writes the Nodes of g as Chrome Trace Event JSON to w, the byte offsets are used as time.
+func (g *Graph) WriteChromeTrace(w io.Writer) error {
+	return writeChromeTrace(w, appendNodeSpans(nil, g.Root, 0), 1.0)
+}
+
+// ------------------------------------------------------------------------------
+type speedscopeEvent struct {
+	Type  string `json:"type"`
+	Frame int    `json:"frame"`
+	At    int64  `json:"at"`
+}
+
+func writeSpeedscope(w io.Writer, name string, unit string, spans []traceSpan) error {
+	frames := []map[string]string{}
+	index := map[string]int{}
+	frame := func(s traceSpan) int {
+		n := s.name
+		if s.failed {
+			n += " (failed)"
+		}
+		i, ok := index[n]
+		if !ok {
+			i = len(frames)
+			index[n] = i
+			frames = append(frames, map[string]string{"name": n})
+		}
+		return i
+	}
+
+	// the events must be ordered, spans of backtracked attempts get clamped to the previous event
+	events := []speedscopeEvent{}
+	stack := []traceSpan{}
+	at := int64(0)
+	pop := func(level int) {
+		for len(stack) > 0 && stack[len(stack)-1].level >= level {
+			s := stack[len(stack)-1]
+			if s.to > at {
+				at = s.to
+			}
+			events = append(events, speedscopeEvent{"C", frame(s), at})
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for _, s := range spans {
+		pop(s.level)
+		if s.from > at {
+			at = s.from
+		}
+		events = append(events, speedscopeEvent{"O", frame(s), at})
+		stack = append(stack, s)
+	}
+	pop(-1)
+
+	start, end := int64(0), int64(0)
+	if len(events) > 0 {
+		start, end = events[0].At, events[len(events)-1].At
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"$schema":  "https://www.speedscope.app/file-format-schema.json",
+		"name":     name,
+		"exporter": "tok",
+		"shared":   map[string]interface{}{"frames": frames},
+		"profiles": []map[string]interface{}{{
+			"type":       "evented",
+			"name":       name,
+			"unit":       unit,
+			"startValue": start,
+			"endValue":   end,
+			"events":     events,
+		}},
+	})
+}
+
+// WriteSpeedscope writes the entries of l as speedscope file to w.
+// Failed attempts get frames with the suffix (failed).
+// On the OffsetAxis are the spans of backtracked attempts clamped to the previous event.
+func (l *Log) WriteSpeedscope(w io.Writer, name string, axis TraceAxis) error {
+	unit := "none"
+	switch axis {
+	case OffsetAxis:
+		unit = "bytes"
+	case TimeAxis:
+		unit = "nanoseconds"
+	}
+	return writeSpeedscope(w, name, unit, l.spans(axis))
+}
+
+// WriteSpeedscope writes the Nodes of g as speedscope file to w, the byte offsets are used as time.
+func (g *Graph) WriteSpeedscope(w io.Writer, name string) error {
+	return writeSpeedscope(w, name, "bytes", appendNodeSpans(nil, g.Root, 0))
+}
@@ -0,0 +1,118 @@
+package tok
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func monitoredLog(t *testing.T, text string) *Log {
+	g := newProfileGrammar()
+	l := &Log{now: fakeClock()}
+	l.Monitor(g.Grammar()...)
+	if err := NewScanner(text).Use(g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return l
+}
+
+func TestLogSteps(t *testing.T) {
+	l := monitoredLog(t, "1,a")
+	exp := [][2]int64{{0, 11}, {1, 4}, {2, 3}, {5, 10}, {6, 7}, {8, 9}}
+	steps := l.steps()
+	if len(steps) != len(exp) {
+		t.Fatalf("expected %v, got %v", exp, steps)
+	}
+	for i, s := range steps {
+		if s != exp[i] {
+			t.Errorf("%d expected %v, got %v", i, exp[i], s)
+		}
+	}
+	if l.Entries[0].ExitAt != 3 {
+		t.Errorf("expected the exit of the root entry at 3, got %d", l.Entries[0].ExitAt)
+	}
+	if l.Entries[5].ExitTime.Sub(l.Entries[5].EnterTime) != time.Millisecond {
+		t.Errorf("unexpected times: %v", l.Entries[5])
+	}
+}
+
+func TestWriteChromeTrace(t *testing.T) {
+	l := monitoredLog(t, "1,a")
+	b := &strings.Builder{}
+	if err := l.WriteChromeTrace(b, StepAxis); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trace := struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}{}
+	if err := json.Unmarshal([]byte(b.String()), &trace); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trace.TraceEvents) != 6 {
+		t.Fatalf("expected 6 events, got %d", len(trace.TraceEvents))
+	}
+	failed := trace.TraceEvents[4]
+	if failed.Name != "number" || failed.Cat != "failed" || failed.Ts != 6 || failed.Dur != 1 {
+		t.Errorf("unexpected failed event: %v", failed)
+	}
+
+	b.Reset()
+	if err := l.WriteChromeTrace(b, TimeAxis); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), `"name":"list","cat":"rule","ph":"X","ts":0,"dur":11000`) {
+		t.Errorf("unexpected time trace: %s", b.String())
+	}
+}
+
+func TestWriteSpeedscope(t *testing.T) {
+	l := monitoredLog(t, "1,a")
+	b := &strings.Builder{}
+	if err := l.WriteSpeedscope(b, "x", StepAxis); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), `{"name":"number (failed)"}`) {
+		t.Errorf("missing failed frame: %s", b.String())
+	}
+	if !strings.Contains(b.String(), `"endValue":11`) {
+		t.Errorf("unexpected end value: %s", b.String())
+	}
+	b.Reset()
+	if err := l.WriteSpeedscope(b, "x", OffsetAxis); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), `"unit":"bytes"`) {
+		t.Errorf("unexpected unit: %s", b.String())
+	}
+
+	g := &Graph{N("root", 0, 5, N("key", 0, 2), N("val", 3, 5))}
+	b.Reset()
+	if err := g.WriteSpeedscope(b, "x"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := `"events":[{"type":"O","frame":0,"at":0},{"type":"O","frame":1,"at":0},{"type":"C","frame":1,"at":2},` +
+		`{"type":"O","frame":2,"at":3},{"type":"C","frame":2,"at":5},{"type":"C","frame":0,"at":5}]`
+	if !strings.Contains(b.String(), exp) {
+		t.Errorf("unexpected graph events: %s", b.String())
+	}
+}
+
+func TestWriteSpeedscopeBacktracking(t *testing.T) {
+	inner := Rule{Name: "inner", Reader: Lit("ab")}
+	outer := Rule{Name: "outer", Reader: Any(Seq(&inner, 'x'), Seq(&inner, 'y'))}
+	l := &Log{}
+	l.Monitor(&inner, &outer)
+	if err := NewScanner("aby").Use(&outer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := &strings.Builder{}
+	if err := l.WriteSpeedscope(b, "x", OffsetAxis); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := `"events":[{"type":"O","frame":0,"at":0},{"type":"O","frame":1,"at":0},{"type":"C","frame":1,"at":2},` +
+		`{"type":"O","frame":1,"at":2},{"type":"C","frame":1,"at":2},{"type":"C","frame":0,"at":3}]`
+	if !strings.Contains(b.String(), exp) {
+		t.Errorf("unexpected events: %s", b.String())
+	}
+}
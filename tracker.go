@@ -9,6 +9,12 @@ type Tracker interface {
 	Update(m Marker)
 }
 
+// RevTracker is a Tracker that can also track the movemend of a Scanner that reads in reverse direction.
+type RevTracker interface {
+	Tracker
+	RevUpdate(m Marker)
+}
+
 // Returns a new empty Basket that is coupled as Tracker on the scanner.
 func (s *Scanner) NewBasket() *Basket {
 	b := &Basket{}
@@ -48,6 +54,23 @@ func (b *Basket) Update(m Marker) {
 	b.segments = []Segment{}
 }
 
+// RevUpdate removes the picked Segments that a Scanner in reverse direction has left behind m.
+func (b *Basket) RevUpdate(m Marker) {
+	for i := len(b.segments); i > 0; i-- {
+		seg := b.segments[i-1]
+		if seg.from >= m {
+			b.segments = b.segments[:i]
+			return
+		}
+	}
+	b.segments = []Segment{}
+}
+
+// Reset removes all picked Segments.
+func (b *Basket) Reset() {
+	b.segments = []Segment{}
+}
+
 // Picked returns the picked Segments.
 func (b *Basket) Picked() []Segment {
 	return b.segments
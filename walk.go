@@ -0,0 +1,429 @@
+package tok
+
+// ParentReader is a Reader that uses other Readers.
+// WrapReaders, the Coverage and the Debugger of the debug package walk through the Readers that a ParentReader uses.
+// All Readers of this package implement ParentReader, a Reader of another package that uses other Readers should implement it too.
+type ParentReader interface {
+	Reader
+	// SubReaders returns the Readers that the Reader uses directly, the value is nil if the Reader uses no other Readers.
+	SubReaders() []Reader
+	// WithSubReaders returns a copy of the Reader that uses subs instead of the Readers of SubReaders.
+	// Returns nil if the Reader can not be copied.
+	WithSubReaders(subs []Reader) Reader
+}
+
+// subReaders returns the Readers that r uses directly.
+// A Rule has no sub Readers, the Reader of a Rule belongs to the Rule, a Reader without ParentReader has none either.
+func subReaders(r Reader) []Reader {
+	if _, ok := r.(*Rule); ok {
+		return nil
+	}
+	if p, ok := r.(ParentReader); ok {
+		return p.SubReaders()
+	}
+	return nil
+}
+
+// withSubReaders returns a copy of r that uses subs instead of the Readers of subReaders.
+// Returns nil if r can not be copied.
+func withSubReaders(r Reader, subs []Reader) Reader {
+	if p, ok := r.(ParentReader); ok {
+		return p.WithSubReaders(subs)
+	}
+	return nil
+}
+
+// walkable reports if the Readers that r uses can be walked, a Rule ends a walk.
+func walkable(r Reader) bool {
+	if _, ok := r.(*Rule); ok {
+		return true
+	}
+	_, ok := r.(ParentReader)
+	return ok
+}
+
+// walkReaders calls f for r and all Readers that r uses, the walk stops at Rules.
+func walkReaders(r Reader, f func(r Reader)) {
+	f(r)
+	for _, sub := range subReaders(r) {
+		walkReaders(sub, f)
+	}
+}
+
+// keepsState reports if r keeps a state that must not be copied, like the Items of a SepByReader.
+func keepsState(r Reader) bool {
+	switch r.(type) {
+	case *SepByReader, *RepeatReader, *janusBeginReader, *janusEndReader:
+		return true
+	}
+	return false
+}
+
+// WrapReaders returns a copy of r where f wraps each Reader that r uses.
+// The copy stops at Rules. The Readers that a Reader with a state uses, like SepByReader, Repeat or Janus,
+// and Readers that do not implement ParentReader are not walked, WrapReaders returns them as second value.
+// r itself is not changed, Readers that r shares with other Rules keep their behavior.
+func WrapReaders(r Reader, f func(r Reader) Reader) (Reader, []Reader) {
+	unwalked := []Reader{}
+	res := wrapReaders(r, f, &unwalked)
+	return res, unwalked
+}
+
+func wrapReaders(r Reader, f func(r Reader) Reader, unwalked *[]Reader) Reader {
+	subs := subReaders(r)
+	if !walkable(r) || (len(subs) > 0 && keepsState(r)) {
+		*unwalked = append(*unwalked, r)
+		return r
+	}
+	if len(subs) == 0 {
+		return r
+	}
+	wrapped := make([]Reader, len(subs))
+	for i, sub := range subs {
+		wrapped[i] = sub
+		if _, ok := sub.(*Rule); !ok {
+			wrapped[i] = f(wrapReaders(sub, f, unwalked))
+		}
+	}
+	c := withSubReaders(r, wrapped)
+	if c == nil {
+		*unwalked = append(*unwalked, r)
+		return r
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+func (r atEndReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r atEndReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r betweenReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r betweenReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r foldReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r foldReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r holeyReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r holeyReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r invalidReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r invalidReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r litReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r litReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r matchReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r matchReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r runeReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r runeReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r wrapReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r wrapReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r *anyRuneReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r *anyRuneReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r *betweenAnyReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r *betweenAnyReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r *BoolReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r *BoolReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r *IntReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r *IntReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+func (r *UintReader) SubReaders() []Reader {
+	return nil
+}
+
+func (r *UintReader) WithSubReaders(subs []Reader) Reader {
+	return r
+}
+
+//------------------------------------------------------------------------------
+
+func (r *atReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *atReader) WithSubReaders(subs []Reader) Reader {
+	return &atReader{subs[0]}
+}
+
+func (r *behindReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *behindReader) WithSubReaders(subs []Reader) Reader {
+	return &behindReader{subs[0]}
+}
+
+func (r *notBehindReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *notBehindReader) WithSubReaders(subs []Reader) Reader {
+	return &notBehindReader{subs[0]}
+}
+
+func (r *notReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *notReader) WithSubReaders(subs []Reader) Reader {
+	return &notReader{subs[0]}
+}
+
+func (r *optReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *optReader) WithSubReaders(subs []Reader) Reader {
+	return &optReader{subs[0]}
+}
+
+func (r *pastReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *pastReader) WithSubReaders(subs []Reader) Reader {
+	return &pastReader{subs[0]}
+}
+
+func (r *revReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *revReader) WithSubReaders(subs []Reader) Reader {
+	return &revReader{subs[0]}
+}
+
+func (r *toReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *toReader) WithSubReaders(subs []Reader) Reader {
+	return &toReader{subs[0]}
+}
+
+func (r manyReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r manyReader) WithSubReaders(subs []Reader) Reader {
+	return &manyReader{subs[0]}
+}
+
+func (r zomReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r zomReader) WithSubReaders(subs []Reader) Reader {
+	return &zomReader{subs[0]}
+}
+
+func (r *anyReader) SubReaders() []Reader {
+	return r.readers
+}
+
+func (r *anyReader) WithSubReaders(subs []Reader) Reader {
+	return &anyReader{subs}
+}
+
+func (r *seqReader) SubReaders() []Reader {
+	return r.readers
+}
+
+func (r *seqReader) WithSubReaders(subs []Reader) Reader {
+	return &seqReader{subs}
+}
+
+func (r *skipSeqReader) SubReaders() []Reader {
+	return append([]Reader{r.skip}, r.readers...)
+}
+
+func (r *skipSeqReader) WithSubReaders(subs []Reader) Reader {
+	return &skipSeqReader{subs[0], subs[1:]}
+}
+
+func (r *bodyReader) SubReaders() []Reader {
+	return []Reader{r.body, r.tail}
+}
+
+func (r *bodyReader) WithSubReaders(subs []Reader) Reader {
+	return &bodyReader{subs[0], subs[1]}
+}
+
+func (r *bodyTailReader) SubReaders() []Reader {
+	return []Reader{r.body, r.tail}
+}
+
+func (r *bodyTailReader) WithSubReaders(subs []Reader) Reader {
+	return &bodyTailReader{subs[0], subs[1]}
+}
+
+// The readers of Janus share the captured sub string, a copy of one of them would be separated from the other.
+func (r *janusBeginReader) SubReaders() []Reader {
+	return []Reader{r.reader}
+}
+
+func (r *janusBeginReader) WithSubReaders(subs []Reader) Reader {
+	return nil
+}
+
+func (r *janusEndReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *janusEndReader) WithSubReaders(subs []Reader) Reader {
+	return nil
+}
+
+func (r *mapReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *mapReader) WithSubReaders(subs []Reader) Reader {
+	return &mapReader{subs[0], r.f}
+}
+
+func (r *namedReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *namedReader) WithSubReaders(subs []Reader) Reader {
+	return &namedReader{r.name, subs[0]}
+}
+
+func (r *timesReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *timesReader) WithSubReaders(subs []Reader) Reader {
+	return &timesReader{r.n, subs[0]}
+}
+
+// The copy of a RepeatReader has its own Items.
+func (r *RepeatReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *RepeatReader) WithSubReaders(subs []Reader) Reader {
+	return &RepeatReader{Min: r.Min, Max: r.Max, sub: subs[0]}
+}
+
+// The copy of a SepByReader has its own Items.
+func (r *SepByReader) SubReaders() []Reader {
+	return []Reader{r.item, r.sep}
+}
+
+func (r *SepByReader) WithSubReaders(subs []Reader) Reader {
+	return &SepByReader{Min: r.Min, Trail: r.Trail, item: subs[0], sep: subs[1]}
+}
+
+func (r *monitorReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *monitorReader) WithSubReaders(subs []Reader) Reader {
+	return &monitorReader{r.info, r.log, subs[0]}
+}
+
+func (r *pickReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *pickReader) WithSubReaders(subs []Reader) Reader {
+	return &pickReader{r.info, r.basket, subs[0]}
+}
+
+func (r *profileReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *profileReader) WithSubReaders(subs []Reader) Reader {
+	return &profileReader{r.name, r.p, subs[0]}
+}
+
+func (r *coverReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r *coverReader) WithSubReaders(subs []Reader) Reader {
+	return &coverReader{r.hits, subs[0]}
+}
+
+func (r ruleNameReader) SubReaders() []Reader {
+	return []Reader{r.sub}
+}
+
+func (r ruleNameReader) WithSubReaders(subs []Reader) Reader {
+	return &ruleNameReader{subs[0]}
+}